@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+	"github.com/foxinuni/prueba-patrones/internal/migrations"
+	"github.com/foxinuni/prueba-patrones/internal/parsers"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const flushTimeout = 30 * time.Second
+
+// importOptions are the flags shared by both import commands.
+type importOptions struct {
+	file          string
+	rejectsPath   string
+	rejectsTable  bool
+	replace       bool
+	transactional bool
+	stage         bool
+	merge         bool
+	mergeRun      int
+	dryRun        bool
+	store         string
+	progress      time.Duration
+}
+
+func (o *importOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.file, "file", "", "Path to import file")
+	fs.StringVar(&o.rejectsPath, "rejects", "", "Path to a CSV file where rejected records are written")
+	fs.BoolVar(&o.rejectsTable, "rejects-db", false, "Write rejected records to the rejects table")
+	fs.BoolVar(&o.replace, "replace", false, "Replace the rows of a previous import of the same file, in one transaction (implies -tx)")
+	fs.BoolVar(&o.transactional, "tx", false, "Import the whole file in one transaction, leaving the database untouched on failure")
+	fs.BoolVar(&o.stage, "stage", false, "Load into a staging table and validate it instead of importing directly")
+	fs.BoolVar(&o.merge, "merge", false, "Merge the staged rows when they pass validation (with -stage)")
+	fs.IntVar(&o.mergeRun, "merge-run", 0, "Validate and merge the staging table of a previous run")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Parse the file and print what would be imported without touching the database")
+	fs.StringVar(&o.store, "store", "batch", "Store used to insert entries (batch, copy)")
+	fs.DurationVar(&o.progress, "progress", 5*time.Second, "Interval between progress lines, 0 disables them")
+}
+
+// validate checks the flags that do not depend on the kind of import.
+func (o *importOptions) validate() error {
+	if o.file == "" && o.mergeRun == 0 {
+		return newUsageError("-file or -merge-run argument must be set")
+	}
+
+	if o.store != "batch" && o.store != "copy" {
+		return newUsageError("store %q not supported", o.store)
+	}
+
+	if o.replace && o.stage && !o.merge {
+		return newUsageError("-replace with -stage requires -merge, the previous rows would be deleted before the staged ones are merged")
+	}
+
+	return nil
+}
+
+// inTransaction reports whether the import runs in one transaction. Replacing a previous import
+// always does, so its rows are only deleted once the new ones are stored.
+func (o *importOptions) inTransaction() bool {
+	return o.transactional || o.replace
+}
+
+// newStore creates the store selected with -store, sending up to maxInFlight batches at once
+// and retrying each one up to maxRetries times.
+func newStore[T any](db internal.Querier, config *Config, kind string, maxInFlight int, maxRetries int, mapper internal.RowMapper[T]) internal.EntryStore[T] {
+	retry := internal.NewRetryPolicy(maxRetries)
+	if kind == "copy" {
+		return internal.NewPgCopyStore(db, mapper, config.BatchSize, maxInFlight, retry, flushTimeout)
+	}
+
+	return internal.NewPgBufferedStore(db, mapper, config.BatchSize, maxInFlight, retry, flushTimeout)
+}
+
+// importJob describes what differs between importing program entries and population rows.
+type importJob[T any] struct {
+	kind    string
+	program int
+	target  string
+	staging internal.StagingSpec
+	parser  internal.EntryParser[T]
+	rows    func(table string, runID int) internal.RowMapper[T]
+}
+
+func runImport(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return newUsageError("usage: import program|population [flags]")
+	}
+
+	switch args[0] {
+	case "program":
+		return runImportProgram(ctx, args[1:])
+	case "population":
+		return runImportPopulation(ctx, args[1:])
+	default:
+		return newUsageError("unknown import %q, expected program or population", args[0])
+	}
+}
+
+func runImportProgram(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import program", flag.ContinueOnError)
+	resolveConfig := configFlags(fs)
+
+	var options importOptions
+	options.register(fs)
+
+	program := fs.Int("prog", -1, "Number of program to parse as")
+	mappingPath := fs.String("mapping", "", "Path to a column mapping file (overrides -prog)")
+	since := fs.String("since", "1900-01-01", "Earliest entry date accepted by the staging validation")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	if *program == -1 && *mappingPath == "" && options.mergeRun == 0 {
+		return newUsageError("-prog or -mapping argument must be set")
+	}
+
+	config, err := resolveConfig()
+	if err != nil {
+		return err
+	}
+
+	windowStart, err := time.Parse(time.DateOnly, *since)
+	if err != nil {
+		return newUsageError("invalid -since date %q", *since)
+	}
+
+	// parse without a database
+	if options.dryRun {
+		mapping, err := loadMapping(*program, *mappingPath)
+		if err != nil {
+			return err
+		}
+
+		reference, err := migrations.ReferenceData()
+		if err != nil {
+			return err
+		}
+
+		rejects, closeRejects, err := csvRejects(options.rejectsPath)
+		if err != nil {
+			return err
+		}
+		defer closeRejects()
+
+		return validateFile(ctx, options.file, parsers.NewProgramParser(mapping, reference), internal.NewProgramStats(), rejects, config.Workers, options.progress)
+	}
+
+	pool, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	staging := internal.EntriesStaging(windowStart, time.Now())
+
+	// merge a previously staged run
+	if options.mergeRun != 0 {
+		return mergeStaged(ctx, pool, options.mergeRun, internal.RunKindProgram, staging)
+	}
+
+	// load mapping
+	mapping, err := loadMapping(*program, *mappingPath)
+	if err != nil {
+		return err
+	}
+
+	// load reference data
+	reference, err := internal.NewPgReferenceStore(pool).Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	return importFile(ctx, pool, config, &options, importJob[internal.ProgramEntry]{
+		kind:    internal.RunKindProgram,
+		program: mapping.Program,
+		target:  "entries",
+		staging: staging,
+		parser:  parsers.NewProgramParser(mapping, reference),
+		rows:    internal.ProgramRows,
+	})
+}
+
+func runImportPopulation(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import population", flag.ContinueOnError)
+	resolveConfig := configFlags(fs)
+
+	var options importOptions
+	options.register(fs)
+
+	skipCity := fs.Bool("skip-city", false, "Skip the city-wide aggregate rows (CODIGO_LOCALIDAD 0)")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	config, err := resolveConfig()
+	if err != nil {
+		return err
+	}
+
+	// parse without a database
+	if options.dryRun {
+		reference, err := migrations.ReferenceData()
+		if err != nil {
+			return err
+		}
+
+		rejects, closeRejects, err := csvRejects(options.rejectsPath)
+		if err != nil {
+			return err
+		}
+		defer closeRejects()
+
+		return validateFile(ctx, options.file, parsers.NewPopulationParser(*skipCity, reference), internal.NewPopulationStats(), rejects, config.Workers, options.progress)
+	}
+
+	pool, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	// merge a previously staged run
+	if options.mergeRun != 0 {
+		return mergeStaged(ctx, pool, options.mergeRun, internal.RunKindPopulation, internal.PopulationStaging())
+	}
+
+	// load reference data
+	reference, err := internal.NewPgReferenceStore(pool).Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	return importFile(ctx, pool, config, &options, importJob[internal.PopulationEntry]{
+		kind:    internal.RunKindPopulation,
+		target:  "population",
+		staging: internal.PopulationStaging(),
+		parser:  parsers.NewPopulationParser(*skipCity, reference),
+		rows:    internal.PopulationRows,
+	})
+}
+
+// importFile runs a whole import: ledger checks, optional transaction and staging, the import itself and its summary.
+func importFile[T any](ctx context.Context, pool *pgxpool.Pool, config *Config, options *importOptions, job importJob[T]) error {
+	errorBudget := config.MaxErrors
+
+	// check the file was not imported already
+	hash, err := internal.HashFile(options.file)
+	if err != nil {
+		return err
+	}
+
+	runs := internal.NewPgRunStore(pool)
+	previous, err := runs.FindRun(ctx, job.kind, job.program, hash)
+	if err != nil {
+		return err
+	}
+
+	if previous != nil && !options.replace {
+		return fmt.Errorf("%s was already imported by run %d (%s), use -replace to import it again", options.file, previous.ID, previous.Status)
+	}
+
+	// rows are written through db, a single transaction in all-or-nothing mode
+	var db internal.Querier = pool
+	var tx pgx.Tx
+	maxInFlight, maxRetries := config.MaxInFlight, config.MaxRetries
+	if options.inTransaction() {
+		if tx, err = pool.Begin(ctx); err != nil {
+			return err
+		}
+
+		// a failed statement aborts the transaction, so there is no point in carrying on
+		errorBudget = 0
+		defer tx.Rollback(context.Background())
+		db = tx
+
+		// a transaction is a single connection, its batches go one at a time and
+		// a failed batch aborts it, so retrying is pointless
+		maxInFlight, maxRetries = 1, 0
+	}
+
+	// deleted for good only when the transaction commits
+	if previous != nil {
+		if err := internal.NewPgRunStore(db).ReplaceRun(ctx, previous); err != nil {
+			return err
+		}
+	}
+
+	// start run
+	run := &internal.ImportRun{Kind: job.kind, Program: job.program, File: options.file, Hash: hash}
+	if err := runs.StartRun(ctx, run); err != nil {
+		return err
+	}
+
+	// load into a staging table when staging
+	table := job.target
+	var stager *internal.PgStager
+	if options.stage {
+		stager = internal.NewPgStager(db, job.staging, run.ID)
+		if err := stager.Create(ctx); err != nil {
+			return err
+		}
+
+		table = stager.Table()
+	}
+
+	// create store
+	store := newStore(db, config, options.store, maxInFlight, maxRetries, job.rows(table, run.ID))
+
+	// create rejects writers
+	rejects, closeRejects, err := csvRejects(options.rejectsPath)
+	if err != nil {
+		return err
+	}
+	defer closeRejects()
+
+	if options.rejectsTable {
+		rejects = append(rejects, internal.NewPgRejectStore(pool, run.ID))
+	}
+
+	// create import controller
+	controller := internal.NewImportController(store, job.parser, rejects, config.Workers, errorBudget)
+
+	// yeet the data to the database >:D (the store is flushed even after an interrupt)
+	stopProgress := showProgress(options.progress, controller.Progress)
+	err = controller.Import(ctx, options.file)
+	stopProgress()
+
+	// record the run
+	run.Stored = controller.Stored()
+	run.Rejected = controller.Rejections().Total()
+
+	// store failures within the error budget are recorded but do not fail the run
+	var tolerated *internal.ImportError
+	if errors.As(err, &tolerated) {
+		run.Failed = tolerated.FailedRows
+		if tolerated.Aborted {
+			tolerated = nil
+		} else {
+			err = nil
+		}
+	}
+
+	// validate the staged rows, merging them if asked to
+	status := internal.RunStatus(err)
+	if stager != nil && err == nil {
+		status, err = finishStaging(ctx, stager, options.merge)
+	}
+
+	// commit the transaction only if everything was stored
+	if tx != nil {
+		if err == nil {
+			err = tx.Commit(context.Background())
+		}
+
+		if err != nil {
+			tx.Rollback(context.Background())
+			status = internal.RunStatus(err)
+			run.Stored, run.Failed = 0, 0
+		}
+	}
+
+	if err := runs.FinishRun(context.Background(), run, status); err != nil {
+		return err
+	}
+
+	controller.Progress().Print(os.Stdout)
+	controller.Rejections().Print(os.Stdout)
+	if reporter, ok := job.parser.(internal.UnresolvedReporter); ok {
+		reporter.PrintUnresolved(os.Stdout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if tolerated != nil {
+		fmt.Printf("Done, %d store failures (%d rows lost) within the error budget\n", tolerated.Failures, tolerated.FailedRows)
+		return nil
+	}
+
+	fmt.Println("Done!")
+	return nil
+}
+
+func loadMapping(program int, mappingPath string) (*parsers.ProgramMapping, error) {
+	if mappingPath != "" {
+		return parsers.LoadProgramMapping(mappingPath)
+	}
+
+	return parsers.BuiltinProgramMapping(program)
+}
+
+// finishStaging validates the staged rows and merges them when merge is set, returning the run status.
+func finishStaging(ctx context.Context, stager *internal.PgStager, merge bool) (string, error) {
+	if !merge {
+		results, err := stager.Validate(ctx)
+		if err != nil {
+			return internal.RunFailed, err
+		}
+
+		internal.PrintValidation(os.Stdout, stager.Table(), results)
+		fmt.Printf("Staged into %s, merge it with -merge-run once inspected\n", stager.Table())
+		return internal.RunStaged, nil
+	}
+
+	results, _, err := stager.MergeValid(ctx)
+	if results != nil {
+		internal.PrintValidation(os.Stdout, stager.Table(), results)
+	}
+
+	if errors.Is(err, internal.ErrInvalidStaging) {
+		return internal.RunStaged, err
+	}
+
+	return internal.RunStatus(err), err
+}
+
+// mergeStaged validates and merges the staging table of a staged run.
+func mergeStaged(ctx context.Context, pool *pgxpool.Pool, id int, kind string, spec internal.StagingSpec) error {
+	runs := internal.NewPgRunStore(pool)
+	run, err := runs.GetRun(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if run.Kind != kind || run.Status != internal.RunStaged {
+		return fmt.Errorf("run %d is a %s run with status %s, not a staged %s run", run.ID, run.Kind, run.Status, kind)
+	}
+
+	stager := internal.NewPgStager(pool, spec, run.ID)
+	results, merged, err := stager.MergeValid(ctx)
+	if results != nil {
+		internal.PrintValidation(os.Stdout, stager.Table(), results)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	run.Stored = int(merged)
+	if err := runs.FinishRun(context.Background(), run, internal.RunCompleted); err != nil {
+		return err
+	}
+
+	fmt.Printf("Merged %d rows from %s\n", merged, stager.Table())
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "import", usage: "import program|population [flags]", run: runImport},
+	{name: "migrate", usage: "migrate [flags] up|down|status|baseline", run: runMigrate},
+	{name: "report", usage: "report [flags]", run: runReport},
+	{name: "validate", usage: "validate program|population [flags]", run: runValidate},
+}
+
+// usageError is returned for malformed command lines, it exits with status 2.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// parseFlags parses args, the flag package already reports malformed flags so the usage error is silent.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return &usageError{}
+	}
+
+	return err
+}
+
+func main() {
+	// cancel the running command on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:])
+	stop()
+
+	os.Exit(exitCode(err))
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage()
+		return newUsageError("a command is required")
+	}
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(ctx, args[1:])
+		}
+	}
+
+	usage()
+	return newUsageError("unknown command %q", args[0])
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command>\n\nCommands:\n", os.Args[0])
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", command.usage)
+	}
+}
+
+func exitCode(err error) int {
+	var usageErr *usageError
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usageErr):
+		if usageErr.message != "" {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		return 2
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		return internal.ExitCode(err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/foxinuni/prueba-patrones/internal/migrations"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	resolveConfig := configFlags(fs)
+
+	steps := fs.Int("steps", 1, "Number of migrations reverted by down")
+	baseline := fs.Int("version", 1, "Version marked as applied by baseline")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return newUsageError("usage: migrate [flags] up|down|status|baseline")
+	}
+
+	config, err := resolveConfig()
+	if err != nil {
+		return err
+	}
+
+	pool, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	// create migrator
+	migrator, err := migrations.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	switch operation := fs.Arg(0); operation {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		migrations.PrintStatus(os.Stdout, statuses)
+		return nil
+	case "baseline":
+		return migrator.Baseline(ctx, *baseline)
+	default:
+		return newUsageError("unknown migrate operation %q", operation)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+// showProgress logs the progress every interval until the returned function is called,
+// a non positive interval shows nothing.
+func showProgress(interval time.Duration, progress func() internal.Progress) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				log.Printf("Progress: %s", progress())
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
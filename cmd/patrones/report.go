@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+	"github.com/jackc/pgx/v5"
+)
+
+var reportViews = map[string]string{
+	"indicadores":  "VISTA_INDICADORES",
+	"consolidado":  "VISTA_CONSOLIDADO",
+	"consistencia": "VISTA_CONSISTENCIA_POBLACION",
+	"poblacion":    "VISTA_POBLACION_ANUAL",
+}
+
+func runReport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	resolveConfig := configFlags(fs)
+
+	view := fs.String("view", "indicadores", "View to export (indicadores, consolidado, consistencia, poblacion)")
+	out := fs.String("out", "", "Path of the CSV file to write (defaults to stdout)")
+	bandSpec := fs.String("bands", "", "Age bands of the indicators: five, life or custom ranges such as 0-17,18-59,60-")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	name, ok := reportViews[*view]
+	if !ok {
+		return newUsageError("unknown view %q", *view)
+	}
+
+	var bands []internal.AgeBand
+	if *bandSpec != "" {
+		if *view != "indicadores" {
+			return newUsageError("-bands only applies to the indicadores view")
+		}
+
+		var err error
+		if bands, err = internal.ParseAgeBands(*bandSpec); err != nil {
+			return newUsageError("%v", err)
+		}
+	}
+
+	config, err := resolveConfig()
+	if err != nil {
+		return err
+	}
+
+	pool, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	// the simple protocol returns every value in its text format
+	query, queryArgs := "SELECT * FROM "+name, []any{}
+	if bands != nil {
+		query, queryArgs = bandedIndicators(bands)
+	}
+
+	rows, err := pool.Query(ctx, query, append([]any{pgx.QueryExecModeSimpleProtocol}, queryArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return writeCSV(w, rows)
+}
+
+// bandedIndicators returns the indicators query grouping single ages into bands.
+func bandedIndicators(bands []internal.AgeBand) (string, []any) {
+	names := make([]string, len(bands))
+	mins := make([]int32, len(bands))
+	maxs := make([]int32, len(bands))
+	for i, band := range bands {
+		names[i] = band.Name
+		mins[i] = int32(band.Min)
+		maxs[i] = int32(band.Max)
+	}
+
+	return `
+		SELECT
+			r.year,
+			CONCAT(d.id, '- ', d.name) district,
+			COALESCE(g.name, 'Total') gender,
+			b.name age_range,
+			SUM(r.entries) entries,
+			SUM(r.population) population,
+			(SUM(r.entries) * 100.0 / SUM(r.population)) percentage
+		FROM VISTA_INDICADORES_EDAD r
+		INNER JOIN unnest($1::text[], $2::int[], $3::int[]) WITH ORDINALITY AS b(name, min_age, max_age, position)
+			ON r.age BETWEEN b.min_age AND b.max_age
+		INNER JOIN districts d ON r.district_id = d.id
+		LEFT JOIN gender g ON r.gender_id = g.id
+		GROUP BY r.year, b.position, b.name, d.id, d.name, g.id, g.name
+		ORDER BY r.year, b.position, d.id, g.id NULLS LAST
+	`, []any{names, mins, maxs}
+}
+
+// writeCSV writes the rows of a text format query, with the column names as header.
+func writeCSV(w io.Writer, rows pgx.Rows) error {
+	writer := csv.NewWriter(w)
+
+	fields := rows.FieldDescriptions()
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.Name
+	}
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		raw := rows.RawValues()
+
+		record := make([]string, len(raw))
+		for i, value := range raw {
+			record[i] = string(value)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+	"github.com/foxinuni/prueba-patrones/internal/migrations"
+	"github.com/foxinuni/prueba-patrones/internal/parsers"
+)
+
+// runValidate parses a file end-to-end without touching the database.
+func runValidate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return newUsageError("usage: validate program|population [flags]")
+	}
+
+	fs := flag.NewFlagSet("validate "+args[0], flag.ContinueOnError)
+	file := fs.String("file", "", "Path to the file to validate")
+	rejectsPath := fs.String("rejects", "", "Path to a CSV file where rejected records are written")
+	workers := fs.Int("workers", 8, "Number of workers")
+	progress := fs.Duration("progress", 5*time.Second, "Interval between progress lines, 0 disables them")
+
+	var program *int
+	var mappingPath *string
+	var skipCity *bool
+	switch args[0] {
+	case "program":
+		program = fs.Int("prog", -1, "Number of program to parse as")
+		mappingPath = fs.String("mapping", "", "Path to a column mapping file (overrides -prog)")
+	case "population":
+		skipCity = fs.Bool("skip-city", false, "Skip the city-wide aggregate rows (CODIGO_LOCALIDAD 0)")
+	}
+
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return newUsageError("-file argument must be set")
+	}
+
+	if *workers < 1 {
+		return newUsageError("workers must be at least 1, got %d", *workers)
+	}
+
+	// the reference data a freshly migrated database would hold
+	reference, err := migrations.ReferenceData()
+	if err != nil {
+		return err
+	}
+
+	// create rejects writer
+	rejects, closeRejects, err := csvRejects(*rejectsPath)
+	if err != nil {
+		return err
+	}
+	defer closeRejects()
+
+	switch args[0] {
+	case "program":
+		if *program == -1 && *mappingPath == "" {
+			return newUsageError("-prog or -mapping argument must be set")
+		}
+
+		mapping, err := loadMapping(*program, *mappingPath)
+		if err != nil {
+			return err
+		}
+
+		return validateFile(ctx, *file, parsers.NewProgramParser(mapping, reference), internal.NewProgramStats(), rejects, *workers, *progress)
+	case "population":
+		return validateFile(ctx, *file, parsers.NewPopulationParser(*skipCity, reference), internal.NewPopulationStats(), rejects, *workers, *progress)
+	default:
+		return newUsageError("unknown validate %q, expected program or population", args[0])
+	}
+}
+
+// csvRejects opens the CSV reject writer when a path is given, the returned function closes it.
+func csvRejects(path string) (internal.MultiRejectWriter, func() error, error) {
+	if path == "" {
+		return internal.MultiRejectWriter{}, func() error { return nil }, nil
+	}
+
+	writer, err := internal.NewCsvRejectWriter(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return internal.MultiRejectWriter{writer}, writer.Close, nil
+}
+
+// statsStore is a counting sink used instead of a database store.
+type statsStore[T any] interface {
+	internal.EntryStore[T]
+	Print(w io.Writer)
+}
+
+// validateFile runs the parser end-to-end into a counting sink and prints what it would have imported,
+// logging the progress every interval.
+func validateFile[T any](ctx context.Context, file string, parser internal.EntryParser[T], stats statsStore[T], rejects internal.RejectWriter, workers int, interval time.Duration) error {
+	controller := internal.NewImportController[T](stats, parser, rejects, workers, 0)
+
+	stopProgress := showProgress(interval, controller.Progress)
+	err := controller.Import(ctx, file)
+	stopProgress()
+
+	if err != nil && controller.Stored() == 0 && controller.Rejections().Total() == 0 {
+		return err
+	}
+
+	controller.Progress().Print(os.Stdout)
+	stats.Print(os.Stdout)
+	controller.Rejections().Print(os.Stdout)
+	if reporter, ok := parser.(internal.UnresolvedReporter); ok {
+		reporter.PrintUnresolved(os.Stdout)
+	}
+
+	return err
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxAge is the oldest age accepted for an entry.
+const MaxAge = 120
+
+// AgeAt returns the age in completed years of someone born on birth at the given date,
+// failing when the age is negative or implausible.
+func AgeAt(birth time.Time, at time.Time) (int, error) {
+	age := at.Year() - birth.Year()
+
+	// the birthday has not happened yet that year
+	if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+		age--
+	}
+
+	if age < 0 {
+		return 0, fmt.Errorf("birthdate %s is after %s", birth.Format(time.DateOnly), at.Format(time.DateOnly))
+	}
+
+	if age > MaxAge {
+		return 0, fmt.Errorf("age %d is over %d", age, MaxAge)
+	}
+
+	return age, nil
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAgeAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		birth time.Time
+		at    time.Time
+		want  int
+	}{
+		{"birthday reached", date(1990, time.March, 15), date(2024, time.March, 15), 34},
+		{"birthday not reached", date(1990, time.March, 15), date(2024, time.March, 14), 33},
+		{"birthday month not reached", date(1990, time.December, 1), date(2024, time.June, 30), 33},
+		{"born that day", date(2024, time.June, 30), date(2024, time.June, 30), 0},
+		{"leap day before birthday", date(2000, time.February, 29), date(2023, time.February, 28), 22},
+		{"leap day after birthday", date(2000, time.February, 29), date(2023, time.March, 1), 23},
+		{"leap day in a leap year", date(2000, time.February, 29), date(2024, time.February, 29), 24},
+		{"oldest", date(1904, time.January, 1), date(2024, time.January, 1), MaxAge},
+		{"oldest before birthday", date(1903, time.June, 1), date(2024, time.May, 31), MaxAge},
+	}
+
+	for _, tt := range tests {
+		got, err := AgeAt(tt.birth, tt.at)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: AgeAt = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAgeAtRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		birth time.Time
+		at    time.Time
+	}{
+		{"born the next day", date(2024, time.July, 1), date(2024, time.June, 30)},
+		{"born the next year", date(2025, time.January, 1), date(2024, time.June, 30)},
+		{"over the oldest", date(1903, time.January, 1), date(2024, time.January, 1)},
+	}
+
+	for _, tt := range tests {
+		if age, err := AgeAt(tt.birth, tt.at); err == nil {
+			t.Errorf("%s: AgeAt = %d, want an error", tt.name, age)
+		}
+	}
+}
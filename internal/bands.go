@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	LifeCourseEarlyChildhood = 1
+	LifeCourseChildhood      = 2
+	LifeCourseAdolescence    = 3
+	LifeCourseYouth          = 4
+	LifeCourseAdulthood      = 5
+	LifeCourseOldAge         = 6
+)
+
+// AgeBand is a named range of ages, both ends included.
+type AgeBand struct {
+	ID   int
+	Name string
+	Min  int
+	Max  int
+}
+
+// Contains reports whether age falls in the band.
+func (b AgeBand) Contains(age int) bool {
+	return age >= b.Min && age <= b.Max
+}
+
+// LifeCourses are the course of life stages of the population extract (CURSODEVIDA),
+// seeded in the life_courses table.
+var LifeCourses = []AgeBand{
+	{ID: LifeCourseEarlyChildhood, Name: "Primera infancia", Min: 0, Max: 5},
+	{ID: LifeCourseChildhood, Name: "Infancia", Min: 6, Max: 11},
+	{ID: LifeCourseAdolescence, Name: "Adolescencia", Min: 12, Max: 17},
+	{ID: LifeCourseYouth, Name: "Juventud", Min: 18, Max: 28},
+	{ID: LifeCourseAdulthood, Name: "Adultez", Min: 29, Max: 59},
+	{ID: LifeCourseOldAge, Name: "Vejez", Min: 60, Max: MaxAge},
+}
+
+// AgeGroups are the 5-year age groups of the population extract (GRUPOEDAD),
+// seeded in the age_groups table.
+var AgeGroups = fiveYearGroups()
+
+func fiveYearGroups() []AgeBand {
+	groups := []AgeBand{}
+	for min := 0; min < 100; min += 5 {
+		groups = append(groups, AgeBand{ID: len(groups) + 1, Name: fmt.Sprintf("%02d a %02d", min, min+4), Min: min, Max: min + 4})
+	}
+
+	return append(groups, AgeBand{ID: len(groups) + 1, Name: "100 o más", Min: 100, Max: MaxAge})
+}
+
+// FindBand looks a band up by name, ignoring case and surrounding spaces.
+func FindBand(bands []AgeBand, name string) (AgeBand, bool) {
+	name = strings.TrimSpace(name)
+	for _, band := range bands {
+		if strings.EqualFold(band.Name, name) {
+			return band, true
+		}
+	}
+
+	return AgeBand{}, false
+}
+
+// ParseAgeBands returns the banding scheme named by spec: "five" for the 5-year groups,
+// "life" for the course of life stages, or a custom list of ranges such as "0-17,18-59,60-".
+func ParseAgeBands(spec string) ([]AgeBand, error) {
+	switch spec {
+	case "five":
+		return AgeGroups, nil
+	case "life":
+		return LifeCourses, nil
+	}
+
+	bands := []AgeBand{}
+	for i, part := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid age band %q, expected <min>-<max>", part)
+		}
+
+		min, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age band %q: %w", part, err)
+		}
+
+		// an open band goes up to the oldest accepted age
+		band := AgeBand{ID: i + 1, Name: fmt.Sprintf("%d+", min), Min: min, Max: MaxAge}
+		if to != "" {
+			if band.Max, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid age band %q: %w", part, err)
+			}
+
+			band.Name = fmt.Sprintf("%d-%d", min, band.Max)
+		}
+
+		if band.Min < 0 || band.Max < band.Min {
+			return nil, fmt.Errorf("invalid age band %q", part)
+		}
+
+		if i > 0 && band.Min <= bands[i-1].Max {
+			return nil, fmt.Errorf("age band %q overlaps %q", band.Name, bands[i-1].Name)
+		}
+
+		bands = append(bands, band)
+	}
+
+	return bands, nil
+}
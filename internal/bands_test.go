@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAgeBands(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []AgeBand
+	}{
+		{"five", AgeGroups},
+		{"life", LifeCourses},
+		{"0-17,18-59,60-", []AgeBand{
+			{ID: 1, Name: "0-17", Min: 0, Max: 17},
+			{ID: 2, Name: "18-59", Min: 18, Max: 59},
+			{ID: 3, Name: "60+", Min: 60, Max: MaxAge},
+		}},
+		{" 0-4 , 10-14", []AgeBand{
+			{ID: 1, Name: "0-4", Min: 0, Max: 4},
+			{ID: 2, Name: "10-14", Min: 10, Max: 14},
+		}},
+		{"5-5", []AgeBand{{ID: 1, Name: "5-5", Min: 5, Max: 5}}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAgeBands(tt.spec)
+		if err != nil {
+			t.Errorf("ParseAgeBands(%q): %v", tt.spec, err)
+			continue
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseAgeBands(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseAgeBandsRejects(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"ten",
+		"0-17,17-59",
+		"18-59,0-17",
+		"0-17,10-",
+		"10-5",
+		"-1-5",
+		"0-x",
+		"a-5",
+		"0-17,,18-",
+	} {
+		if bands, err := ParseAgeBands(spec); err == nil {
+			t.Errorf("ParseAgeBands(%q) = %v, want an error", spec, bands)
+		}
+	}
+}
+
+func TestAgeGroupsCoverEveryAge(t *testing.T) {
+	for _, bands := range [][]AgeBand{AgeGroups, LifeCourses} {
+		for age := 0; age <= MaxAge; age++ {
+			matches := 0
+			for _, band := range bands {
+				if band.Contains(age) {
+					matches++
+				}
+			}
+
+			if matches != 1 {
+				t.Errorf("age %d falls in %d bands of %v", age, matches, bands)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// diacritics maps the accented letters of the extracts onto their plain letter.
+var diacritics = strings.NewReplacer(
+	"Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "Ñ", "N",
+	"À", "A", "È", "E", "Ì", "I", "Ò", "O", "Ù", "U",
+)
+
+// NormalizeName upper cases a name, strips its diacritics and collapses punctuation and spaces.
+func NormalizeName(name string) string {
+	name = diacritics.Replace(strings.ToUpper(name))
+
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// IsDistrict reports whether id is one of the 20 districts, rather than the city-wide
+// geography of the population aggregates or the unknown district.
+func IsDistrict(id int) bool {
+	return id >= LocationUsaquen && id <= LocationSumapaz
+}
+
+// DistrictResolver maps raw district values onto district ids through their normalized name,
+// an alias, their numeric code or, when a threshold is set, the closest known name.
+// Values it cannot resolve are counted so they can be reported after an import.
+type DistrictResolver struct {
+	aliases    map[string]int
+	names      []string
+	codes      map[int]bool
+	threshold  float64
+	unresolved valueCounter
+}
+
+// NewDistrictResolver creates a resolver for the given aliases, threshold is the lowest similarity
+// (between 0 and 1) accepted for an edit distance match, 0 disables fuzzy matching.
+func NewDistrictResolver(aliases map[string]int, threshold float64) *DistrictResolver {
+	r := &DistrictResolver{
+		aliases:   map[string]int{},
+		codes:     map[int]bool{},
+		threshold: threshold,
+	}
+
+	for alias, id := range aliases {
+		r.aliases[NormalizeName(alias)] = id
+		r.codes[id] = true
+	}
+
+	// sorted so fuzzy matches do not depend on map order
+	for name := range r.aliases {
+		r.names = append(r.names, name)
+	}
+	sort.Strings(r.names)
+
+	return r
+}
+
+// Resolve returns the id of a raw district value.
+func (r *DistrictResolver) Resolve(value string) (int, error) {
+	name := NormalizeName(value)
+
+	// numeric code
+	if code, err := strconv.Atoi(name); err == nil && r.codes[code] {
+		return code, nil
+	}
+
+	// known name or alias
+	if id, ok := r.aliases[name]; ok {
+		return id, nil
+	}
+
+	// closest known name
+	if r.threshold > 0 {
+		if id, ok := r.closest(name); ok {
+			return id, nil
+		}
+	}
+
+	r.unresolved.add(value)
+	return 0, fmt.Errorf("unknown district %q", value)
+}
+
+// closest returns the id of the known name most similar to name, as long as the similarity
+// reaches the threshold and no name of another district is as close.
+func (r *DistrictResolver) closest(name string) (int, bool) {
+	best, bestID, tied := -1.0, 0, false
+	for _, known := range r.names {
+		similarity := Similarity(name, known)
+
+		switch {
+		case similarity > best:
+			best, bestID, tied = similarity, r.aliases[known], false
+		case similarity == best && r.aliases[known] != bestID:
+			tied = true
+		}
+	}
+
+	return bestID, best >= r.threshold && !tied
+}
+
+// Unresolved returns the values that could not be resolved and how many times they were seen.
+func (r *DistrictResolver) Unresolved() map[string]int {
+	return r.unresolved.counts()
+}
+
+// PrintUnresolved writes the unresolved values, most frequent first.
+func (r *DistrictResolver) PrintUnresolved(w io.Writer) {
+	printValues(w, "Unresolved districts", r.Unresolved())
+}
+
+// UnresolvedReporter is implemented by parsers that keep the raw values they could not resolve.
+type UnresolvedReporter interface {
+	PrintUnresolved(w io.Writer)
+}
+
+// valueCounter counts raw values, safe for concurrent use.
+type valueCounter struct {
+	values map[string]int
+	mu     sync.Mutex // Mutex to protect concurrent access to the counts
+}
+
+func (c *valueCounter) add(value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.values == nil {
+		c.values = map[string]int{}
+	}
+
+	c.values[value]++
+}
+
+// counts returns a copy of the counts.
+func (c *valueCounter) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.values)
+}
+
+// printValues writes raw values along with their count, most frequent first.
+func printValues(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+
+		return values[i] < values[j]
+	})
+
+	fmt.Fprintf(w, "%s: %d\n", title, len(values))
+	for _, value := range values {
+		fmt.Fprintf(w, "  %-30q %d\n", value, counts[value])
+	}
+}
+
+// Similarity returns how alike two strings are, from 0 to 1, based on their edit distance.
+func Similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
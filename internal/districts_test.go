@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Engativá", "ENGATIVA"},
+		{"  antonio   nariño ", "ANTONIO NARINO"},
+		{"Los Mártires", "LOS MARTIRES"},
+		{"RAFAEL-URIBE_URIBE", "RAFAEL URIBE URIBE"},
+		{"Ciudad Bolívar.", "CIUDAD BOLIVAR"},
+		{"08", "08"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeName(test.name); got != test.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"KENNEDY", "KENNEDY", 1},
+		{"KENEDY", "KENNEDY", 1 - 1.0/7},
+		{"ENGATVA", "ENGATIVA", 1 - 1.0/8},
+		{"BOSA", "SUBA", 0.25},
+		{"", "", 1},
+		{"", "USME", 0},
+	}
+
+	for _, test := range tests {
+		if got := Similarity(test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+
+		if got := Similarity(test.b, test.a); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestIsDistrict(t *testing.T) {
+	for id, want := range map[int]bool{
+		LocationCity:    false,
+		LocationUsaquen: true,
+		LocationSumapaz: true,
+		21:              false,
+		LocationUnknown: false,
+	} {
+		if got := IsDistrict(id); got != want {
+			t.Errorf("IsDistrict(%d) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestDistrictResolver(t *testing.T) {
+	aliases := map[string]int{
+		"Kennedy":    LocationKennedy,
+		"Engativá":   LocationEngativa,
+		"Santa Fe":   LocationSantaFe,
+		"SANTAFE":    LocationSantaFe,
+		"Bosa":       LocationBosa,
+		"Suba":       LocationSuba,
+		"Candelaria": LocationLaCandelaria,
+	}
+
+	tests := []struct {
+		value     string
+		threshold float64
+		want      int
+		ok        bool
+	}{
+		{"kennedy", 0, LocationKennedy, true},
+		{"ENGATIVA", 0, LocationEngativa, true},
+		{"Santafé", 0, LocationSantaFe, true},
+		{"8", 0, LocationKennedy, true},
+		{"08", 0, LocationKennedy, true},
+		{"0", 0, 0, false},
+		{"99", 0, 0, false},
+		{"Kenedy", 0, 0, false},
+		{"Kenedy", 0.8, LocationKennedy, true},
+		{"Engatva", 0.8, LocationEngativa, true},
+		{"Sanra Fe", 0.8, LocationSantaFe, true},
+		{"Kndy", 0.8, 0, false},
+
+		// as close to BOSA as to SUBA, a tie is not a match
+		{"BUUA", 0.5, 0, false},
+	}
+
+	for _, test := range tests {
+		resolver := NewDistrictResolver(aliases, test.threshold)
+
+		got, err := resolver.Resolve(test.value)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("Resolve(%q) with threshold %v = %d, %v, want %d", test.value, test.threshold, got, err, test.want)
+		}
+
+		if !test.ok && resolver.Unresolved()[test.value] != 1 {
+			t.Errorf("Resolve(%q) was not counted as unresolved", test.value)
+		}
+	}
+}
@@ -1,51 +1,256 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type EntryParser[T any] interface {
-	ParseFile(path string) (<-chan T, error)
+	ParseFile(ctx context.Context, path string) (<-chan T, <-chan Rejection, error)
+}
+
+// ImportError aggregates the store failures of an import.
+type ImportError struct {
+	First      error
+	Failures   int
+	FailedRows int
+	Stored     int
+	Aborted    bool
+}
+
+func (e *ImportError) Error() string {
+	state := "finished"
+	if e.Aborted {
+		state = "aborted"
+	}
+
+	return fmt.Sprintf("import %s with %d store failures (%d rows lost, %d stored): %v", state, e.Failures, e.FailedRows, e.Stored, e.First)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.First
 }
 
 type ImportController[T any] struct {
-	store   EntryStore[T]
-	parser  EntryParser[T]
-	workers int
-	wg      sync.WaitGroup
+	store       EntryStore[T]
+	parser      EntryParser[T]
+	rejects     RejectWriter
+	workers     int
+	errorBudget int
+	wg          sync.WaitGroup
+	parsed      atomic.Int64
+	rejected    atomic.Int64
+	stored      atomic.Int64
+	summary     RejectSummary
+	failure     *ImportError
+	fileSize    int64
+	started     time.Time
+	finished    time.Time
+	mu          sync.Mutex // Mutex to protect concurrent access to the summary, failure and timing
 }
 
-func NewImportController[T any](store EntryStore[T], parser EntryParser[T], workers int) *ImportController[T] {
+// NewImportController creates a controller, rejects may be nil when rejected records only need to be counted.
+// The import is aborted once the store fails more than errorBudget times.
+func NewImportController[T any](store EntryStore[T], parser EntryParser[T], rejects RejectWriter, workers int, errorBudget int) *ImportController[T] {
 	return &ImportController[T]{
-		store:   store,
-		parser:  parser,
-		workers: workers,
-		wg:      sync.WaitGroup{},
+		store:       store,
+		parser:      parser,
+		rejects:     rejects,
+		workers:     workers,
+		errorBudget: errorBudget,
+		wg:          sync.WaitGroup{},
+		summary:     RejectSummary{},
 	}
 }
 
-func (c *ImportController[T]) Import(path string) error {
-	channel, err := c.parser.ParseFile(path)
+// Import stores every entry of the file, once ctx is cancelled the parser stops reading
+// and the remaining entries are drained without being stored. The store is closed at the
+// end when it implements io.Closer. Store failures are returned as an *ImportError.
+func (c *ImportController[T]) Import(parent context.Context, path string) error {
+	// cancelled by the workers when the error budget runs out
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	// the size of the file to tell the progress, unknown when it cannot be stat'd
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+
+	c.mu.Lock()
+	c.fileSize, c.started = size, time.Now()
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.finished = time.Now()
+		c.mu.Unlock()
+	}()
+
+	channel, rejects, err := c.parser.ParseFile(ctx, path)
 	if err != nil {
 		return err
 	}
 
+	c.wg.Add(1)
+	go c.rejectWorker(rejects)
+
 	for i := 0; i < c.workers; i++ {
 		log.Printf("Starting worker %d...", i)
 
 		c.wg.Add(1)
-		go c.worker(channel)
+		go c.worker(ctx, cancel, channel)
 	}
 
 	c.wg.Wait()
-	return nil
+
+	// flush what the store still buffers, even when cancelled
+	if closer, ok := c.store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("error whilst closing store: %v", err)
+			c.fail(err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failure != nil {
+		c.failure.Stored = int(c.stored.Load()) - c.failure.FailedRows
+		return c.failure
+	}
+
+	return parent.Err()
+}
+
+// Stored returns the number of entries handed to the store, minus the rows lost by store failures.
+func (c *ImportController[T]) Stored() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failure != nil {
+		return int(c.stored.Load()) - c.failure.FailedRows
+	}
+
+	return int(c.stored.Load())
+}
+
+// Progress returns the counters of the running import, or of the last one once finished.
+func (c *ImportController[T]) Progress() Progress {
+	c.mu.Lock()
+	progress := Progress{
+		FileSize: c.fileSize,
+		Parsed:   c.parsed.Load(),
+		Rejected: c.rejected.Load(),
+		Stored:   c.stored.Load(),
+	}
+
+	switch {
+	case c.started.IsZero():
+	case c.finished.IsZero():
+		progress.Elapsed = time.Since(c.started)
+	default:
+		progress.Elapsed = c.finished.Sub(c.started)
+	}
+	c.mu.Unlock()
+
+	if counter, ok := c.parser.(ByteCounter); ok {
+		progress.BytesRead = counter.BytesRead()
+	}
+
+	// stores writing each entry right away have flushed what they did not fail to store
+	if counter, ok := c.store.(FlushCounter); ok {
+		progress.Flushed = counter.Flushed()
+	} else {
+		progress.Flushed = int64(c.Stored())
+	}
+
+	return progress
+}
+
+// Rejections returns the number of rejected records per reason.
+func (c *ImportController[T]) Rejections() RejectSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summary := make(RejectSummary, len(c.summary))
+	for reason, count := range c.summary {
+		summary[reason] = count
+	}
+
+	return summary
 }
 
-func (c *ImportController[T]) worker(channel <-chan T) {
+func (c *ImportController[T]) worker(ctx context.Context, cancel context.CancelFunc, channel <-chan T) {
 	for entry := range channel {
-		if err := c.store.CreateEntry(&entry); err != nil {
-			panic(err)
+		c.parsed.Add(1)
+
+		// drain the channel once cancelled
+		if ctx.Err() != nil {
+			continue
+		}
+
+		c.stored.Add(1)
+
+		if err := c.store.CreateEntry(ctx, &entry); err != nil {
+			// a cancelled import is not a store failure, the buffered entry is flushed on close,
+			// but a batch sent before the cancel that failed lost its rows all the same
+			var batchErr *BatchError
+			if ctx.Err() != nil && !errors.As(err, &batchErr) {
+				continue
+			}
+
+			log.Printf("error whilst storing entry: %v", err)
+			if c.fail(err) {
+				cancel()
+			}
+		}
+	}
+
+	c.wg.Done()
+}
+
+// fail records a store failure, reporting whether the error budget is exhausted.
+func (c *ImportController[T]) fail(err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failure == nil {
+		c.failure = &ImportError{First: err}
+	}
+
+	c.failure.Failures++
+	c.failure.FailedRows += FailedRows(err)
+
+	if c.failure.Failures > c.errorBudget {
+		c.failure.Aborted = true
+	}
+
+	return c.failure.Aborted
+}
+
+func (c *ImportController[T]) rejectWorker(rejects <-chan Rejection) {
+	for reject := range rejects {
+		c.parsed.Add(1)
+		c.rejected.Add(1)
+
+		c.mu.Lock()
+		c.summary[reject.Reason]++
+		c.mu.Unlock()
+
+		if c.rejects == nil {
+			continue
+		}
+
+		if err := c.rejects.WriteReject(&reject); err != nil {
+			log.Printf("error whilst writing rejected record: %v", err)
 		}
 	}
 
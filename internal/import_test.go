@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeParser produces count entries, or entries until cancelled when count is 0, waiting pause
+// after the first one.
+type fakeParser struct {
+	count    int
+	pause    time.Duration
+	produced atomic.Int64
+}
+
+func (p *fakeParser) ParseFile(ctx context.Context, path string) (<-chan ProgramEntry, <-chan Rejection, error) {
+	entries := make(chan ProgramEntry)
+	rejects := make(chan Rejection)
+
+	go func() {
+		defer close(entries)
+		defer close(rejects)
+
+		for i := 0; p.count == 0 || i < p.count; i++ {
+			select {
+			case entries <- ProgramEntry{Program: 1, Age: i % 100}:
+				p.produced.Add(1)
+			case <-ctx.Done():
+				return
+			}
+
+			if i == 0 {
+				time.Sleep(p.pause)
+			}
+		}
+	}()
+
+	return entries, rejects, nil
+}
+
+// failingStore fails to store every entry.
+type failingStore struct{}
+
+func (failingStore) CreateEntry(ctx context.Context, entry *ProgramEntry) error {
+	return &BatchError{Batch: 1, Rows: 1, Err: errWrite}
+}
+
+func TestImportErrorBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		budget   int
+		failures int
+		aborted  bool
+	}{
+		{"no budget", 0, 0, 1, true},
+		{"budget exhausted", 0, 2, 3, true},
+		{"within the budget", 3, 5, 3, false},
+	}
+
+	for _, tt := range tests {
+		parser := &fakeParser{count: tt.count}
+		controller := NewImportController[ProgramEntry](failingStore{}, parser, nil, 1, tt.budget)
+
+		// an endless parse only ends when the exhausted budget cancels it
+		err := controller.Import(context.Background(), "")
+
+		var importErr *ImportError
+		if !errors.As(err, &importErr) {
+			t.Fatalf("%s: Import = %v, want an ImportError", tt.name, err)
+		}
+
+		if importErr.Failures != tt.failures || importErr.FailedRows != tt.failures || importErr.Aborted != tt.aborted {
+			t.Errorf("%s: %d failures, %d rows lost, aborted %v, want %d, %d, %v",
+				tt.name, importErr.Failures, importErr.FailedRows, importErr.Aborted, tt.failures, tt.failures, tt.aborted)
+		}
+
+		if controller.Stored() != 0 {
+			t.Errorf("%s: %d entries stored, want 0", tt.name, controller.Stored())
+		}
+	}
+}
+
+func TestImportBackgroundFlushError(t *testing.T) {
+	db := &latencyQuerier{}
+	db.failing.Store(1)
+
+	// the first entry is flushed on timeout while the parser pauses and fails,
+	// the second is written on close
+	store := NewPgBufferedStore(db, ProgramRows("entries", 1), 100, 1, NewRetryPolicy(0), 20*time.Millisecond)
+	controller := NewImportController[ProgramEntry](store, &fakeParser{count: 2, pause: 100 * time.Millisecond}, nil, 1, 5)
+
+	err := controller.Import(context.Background(), "")
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || !errors.Is(err, errWrite) {
+		t.Fatalf("Import = %v, want an ImportError of %v", err, errWrite)
+	}
+
+	if importErr.Failures != 1 || importErr.FailedRows != 1 || importErr.Aborted {
+		t.Errorf("%d failures, %d rows lost, aborted %v, want 1, 1, false", importErr.Failures, importErr.FailedRows, importErr.Aborted)
+	}
+
+	if controller.Stored() != 1 || db.rows.Load() != 1 {
+		t.Errorf("%d entries stored and %d written, want 1", controller.Stored(), db.rows.Load())
+	}
+}
+
+func TestImportCancelledLosesNoRows(t *testing.T) {
+	stores := []struct {
+		name  string
+		store func(db Querier) EntryStore[ProgramEntry]
+	}{
+		{"buffered", func(db Querier) EntryStore[ProgramEntry] {
+			return NewPgBufferedStore(db, ProgramRows("entries", 1), 50, 2, NewRetryPolicy(0), time.Minute)
+		}},
+		{"copy", func(db Querier) EntryStore[ProgramEntry] {
+			return NewPgCopyStore(db, ProgramRows("entries", 1), 50, 2, NewRetryPolicy(0), time.Minute)
+		}},
+	}
+
+	for _, tt := range stores {
+		db := &latencyQuerier{latency: 5 * time.Millisecond}
+		parser := &fakeParser{}
+		controller := NewImportController(tt.store(db), parser, nil, 4, 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := controller.Import(ctx, "")
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: Import = %v, want %v", tt.name, err, context.DeadlineExceeded)
+		}
+
+		// every entry handed to the store, in flight or buffered when cancelled, is written
+		stored := int64(controller.Stored())
+		if stored == 0 || db.rows.Load() != stored || controller.Progress().Flushed != stored {
+			t.Errorf("%s: %d entries stored, %d written and %d flushed, want them equal and not 0",
+				tt.name, stored, db.rows.Load(), controller.Progress().Flushed)
+		}
+
+		if stored > parser.produced.Load() {
+			t.Errorf("%s: %d entries stored out of %d produced", tt.name, stored, parser.produced.Load())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// insurerRule matches the values containing a name.
+type insurerRule struct {
+	name string
+	id   int
+}
+
+// InsurerResolver maps raw insurer values onto insurer ids: an exact alias wins, otherwise the
+// longest rule whose words appear in the value. Rules are tried in a fixed order so the result
+// never depends on map iteration, and the values nothing matched are counted for curation.
+type InsurerResolver struct {
+	aliases   map[string]int
+	rules     []insurerRule
+	unmatched valueCounter
+}
+
+// NewInsurerResolver creates a resolver for the given exact aliases and contains rules.
+func NewInsurerResolver(aliases map[string]int, rules map[string]int) *InsurerResolver {
+	r := &InsurerResolver{
+		aliases: map[string]int{},
+	}
+
+	for alias, id := range aliases {
+		r.aliases[NormalizeName(alias)] = id
+	}
+
+	for name, id := range rules {
+		r.rules = append(r.rules, insurerRule{name: NormalizeName(name), id: id})
+	}
+
+	// longest names first, so SALUD TOTAL is tried before SALUD
+	sort.Slice(r.rules, func(i, j int) bool {
+		if len(r.rules[i].name) != len(r.rules[j].name) {
+			return len(r.rules[i].name) > len(r.rules[j].name)
+		}
+
+		return r.rules[i].name < r.rules[j].name
+	})
+
+	return r
+}
+
+// Resolve returns the id of a raw insurer value, reporting whether anything matched.
+func (r *InsurerResolver) Resolve(value string) (int, bool) {
+	name := NormalizeName(value)
+
+	// exact alias
+	if id, ok := r.aliases[name]; ok {
+		return id, true
+	}
+
+	// whole words only, BOLIVAR does not match BOLIVARIANA
+	padded := " " + name + " "
+	for _, rule := range r.rules {
+		if strings.Contains(padded, " "+rule.name+" ") {
+			return rule.id, true
+		}
+	}
+
+	r.unmatched.add(value)
+	return 0, false
+}
+
+// Unmatched returns the values no alias or rule matched and how many times they were seen.
+func (r *InsurerResolver) Unmatched() map[string]int {
+	return r.unmatched.counts()
+}
+
+// PrintUnresolved writes the unmatched values, most frequent first.
+func (r *InsurerResolver) PrintUnresolved(w io.Writer) {
+	printValues(w, "Unmatched insurers", r.Unmatched())
+}
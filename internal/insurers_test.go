@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestInsurerResolver(t *testing.T) {
+	resolver := NewInsurerResolver(
+		map[string]int{"SANITAS EPS": 7, "Salud Total S.A.": 8},
+		map[string]int{"SALUD": 1, "SALUD TOTAL": 2, "BOLIVAR": 3, "NUEVA EPS": 4, "SANITAS": 5},
+	)
+
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"SALUD TOTAL EPS", 2},
+		{"EPS SALUD TOTAL", 2},
+		{"salud total", 2},
+		{"CAJA DE SALUD", 1},
+		{"SALUD TOTALES", 1},
+		{"SEGUROS BOLIVAR", 3},
+		{"Bolívar S.A.", 3},
+		{"LA NUEVA EPS", 4},
+		{"SANITAS", 5},
+		{"EPS SANITAS", 5},
+		{"sanitas eps", 7},
+		{"SALUD TOTAL S.A.", 8},
+	}
+
+	for _, tt := range tests {
+		got, ok := resolver.Resolve(tt.value)
+		if !ok || got != tt.want {
+			t.Errorf("Resolve(%q) = %d, %v, want %d", tt.value, got, ok, tt.want)
+		}
+	}
+}
+
+func TestInsurerResolverUnmatched(t *testing.T) {
+	resolver := NewInsurerResolver(nil, map[string]int{"BOLIVAR": 3, "NUEVA EPS": 4})
+
+	for _, value := range []string{"BOLIVARIANA", "BOLIVARIANA", "NUEVA", "EPSNUEVA EPS", "BOLIVAR"} {
+		resolver.Resolve(value)
+	}
+
+	want := map[string]int{"BOLIVARIANA": 2, "NUEVA": 1, "EPSNUEVA EPS": 1}
+	if got := resolver.Unmatched(); !maps.Equal(got, want) {
+		t.Errorf("Unmatched() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,267 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration is a versioned schema change, read from sql/<version>_<name>.<up|down>.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells whether a migration was applied, and when.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load returns every embedded migration sorted by version.
+func Load() ([]Migration, error) {
+	paths, err := fs.Glob(files, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, path := range paths {
+		base := strings.TrimPrefix(path, "sql/")
+
+		// split <version>_<name>.<direction>.sql
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", base)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q", base)
+		}
+
+		name, direction, ok := cutLast(name, ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration direction %q", base)
+		}
+
+		data, err := files.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
+
+type Migrator struct {
+	db         internal.Querier
+	migrations []Migration
+}
+
+func NewMigrator(db internal.Querier) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// ensureTable creates the schema_migrations table if needed.
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+
+	return err
+}
+
+// Status returns every migration along with whether it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (MigrationStatus, error) {
+		var status MigrationStatus
+		err := row.Scan(&status.Version, &status.AppliedAt)
+		return status, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := map[int]time.Time{}
+	for _, status := range applied {
+		appliedAt[status.Version] = status.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		at, ok := appliedAt[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: ok, AppliedAt: at}
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration in order, each one in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, m.db, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, status.Up); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, status.Version, status.Name)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s failed: %w", status.Version, status.Name, err)
+		}
+
+		applied = append(applied, status.Migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, m.db, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, status.Down); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, status.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting %04d_%s failed: %w", status.Version, status.Name, err)
+		}
+
+		reverted = append(reverted, status.Migration)
+	}
+
+	return reverted, nil
+}
+
+// Baseline marks the migrations up to version as applied without running them,
+// for databases created by piping the old initialize.sql into psql.
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+
+		_, err := m.db.Exec(ctx, `
+			INSERT INTO schema_migrations (version, name) VALUES ($1, $2)
+			ON CONFLICT (version) DO NOTHING
+		`, migration.Version, migration.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PrintStatus writes one line per migration.
+func PrintStatus(w io.Writer, statuses []MigrationStatus) {
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%04d_%-30s %s\n", status.Version, status.Name, state)
+	}
+}
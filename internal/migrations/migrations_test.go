@@ -0,0 +1,122 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Load() returned no migrations")
+	}
+
+	for i, migration := range migrations {
+		// versions start at 1 without gaps, so baseline -version N means the first N
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, migration.Version, i+1)
+		}
+
+		if migration.Name == "" {
+			t.Errorf("migration %04d has no name", migration.Version)
+		}
+
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %04d_%s has an empty up or down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestInitialIsBaseline(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	// databases created with initialize.sql are baselined at 0001, it must not create anything else
+	for _, table := range []string{"import_runs", "rejects", "run_id"} {
+		if strings.Contains(migrations[0].Up, table) {
+			t.Errorf("0001_%s mentions %s, which initialize.sql did not create", migrations[0].Name, table)
+		}
+	}
+}
+
+func TestCutLast(t *testing.T) {
+	tests := []struct {
+		s, sep        string
+		before, after string
+		ok            bool
+	}{
+		{"initial.up", ".", "initial", "up", true},
+		{"fix.age.down", ".", "fix.age", "down", true},
+		{"initial", ".", "initial", "", false},
+	}
+
+	for _, test := range tests {
+		before, after, ok := cutLast(test.s, test.sep)
+		if before != test.before || after != test.after || ok != test.ok {
+			t.Errorf("cutLast(%q, %q) = %q, %q, %v, want %q, %q, %v", test.s, test.sep, before, after, ok, test.before, test.after, test.ok)
+		}
+	}
+}
+
+func TestReferenceData(t *testing.T) {
+	reference, err := ReferenceData()
+	if err != nil {
+		t.Fatalf("ReferenceData() failed: %v", err)
+	}
+
+	tests := []struct {
+		kind  string
+		value string
+		want  int
+	}{
+		{internal.ReferenceDistrict, "KENNEDY", internal.LocationKennedy},
+		{internal.ReferenceDistrict, "ANTONIO NARINO", internal.LocationAntonioNarino},
+		{internal.ReferenceDistrict, "CANDELARIA", internal.LocationLaCandelaria},
+		{internal.ReferenceDistrict, "BOGOTA", internal.LocationCity},
+		{internal.ReferenceInsurer, "CAPITAL SALUD", internal.EpsCapitalSalud},
+		{internal.ReferenceGender, "FEMALE", internal.SexFemale},
+		{internal.ReferenceGender, "MASCULINO", internal.SexMale},
+		{internal.ReferenceGender, "INTERSEXUAL", internal.SexNonBinary},
+	}
+
+	for _, test := range tests {
+		id, ok := reference.Lookup(test.kind)[test.value]
+		if !ok || id != test.want {
+			t.Errorf("Lookup(%s)[%q] = %d, %v, want %d", test.kind, test.value, id, ok, test.want)
+		}
+	}
+}
+
+func TestReplaySeedsRejectsOtherChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		up   string
+		ok   bool
+	}{
+		{"single row", "INSERT INTO districts (id, name) VALUES (21, 'Nueva');", true},
+		{"alias", "INSERT INTO aliases (kind, alias, target_id) VALUES ('insurer', 'EPS SURA', 6);", true},
+		{"other table", "UPDATE population SET district_id = 0 WHERE district_id = 99;", true},
+		{"column of another table", "ALTER TABLE population ADD COLUMN gender_id INTEGER REFERENCES gender(id);", true},
+		{"several rows", "INSERT INTO districts (id, name) VALUES (21, 'Nueva'), (22, 'Otra');", false},
+		{"insert on two lines", "INSERT INTO gender (id, name)\nVALUES (5, 'Otro');", false},
+		{"rename", "UPDATE insurers SET name = 'Unknown' WHERE id = 0;", false},
+		{"delete", "  delete from aliases WHERE alias = 'SANTAFE';", false},
+		{"truncate", "TRUNCATE TABLE aliases;", false},
+		{"alter", "ALTER TABLE districts RENAME COLUMN name TO label;", false},
+	}
+
+	for _, test := range tests {
+		_, err := replaySeeds([]Migration{{Version: 1, Name: "test", Up: "-- " + test.name + "\n" + test.up + "\n"}})
+		if (err == nil) != test.ok {
+			t.Errorf("%s: replaySeeds() = %v, want ok %v", test.name, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+var (
+	// seedName matches the rows seeded into the districts, insurers and gender tables.
+	seedName = regexp.MustCompile(`(?m)^INSERT INTO (\w+) \(id, name\) VALUES\s*\((\d+), '((?:[^']|'')*)'\);`)
+
+	// seedAlias matches the rows seeded into the aliases table.
+	seedAlias = regexp.MustCompile(`(?m)^INSERT INTO aliases \(kind, alias, target_id\) VALUES \('(\w+)', '((?:[^']|'')*)', (\d+)\);`)
+
+	// seedChange matches every statement changing the rows or the shape of a table.
+	seedChange = regexp.MustCompile(`(?im)^[ \t]*(INSERT\s+INTO|UPDATE|DELETE\s+FROM|TRUNCATE(?:\s+TABLE)?|ALTER\s+TABLE|DROP\s+TABLE|COPY)\s+(?:ONLY\s+)?(?:IF\s+EXISTS\s+)?(\w+)`)
+)
+
+// ReferenceData returns the names and aliases the migrations seed, the reference data of a
+// freshly migrated database. It is used when validating files without one, so both read the
+// same spellings.
+func ReferenceData() (*internal.ReferenceData, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return replaySeeds(migrations)
+}
+
+// replaySeeds replays the single row inserts into the reference tables. Any other change to
+// them fails, rather than leaving the reference data silently out of date.
+func replaySeeds(migrations []Migration) (*internal.ReferenceData, error) {
+	kinds := map[string]string{}
+	data := &internal.ReferenceData{
+		Names:   map[string]map[string]int{},
+		Aliases: map[string]map[string]int{},
+	}
+
+	for kind, table := range internal.ReferenceTables {
+		kinds[table] = kind
+		data.Names[kind] = map[string]int{}
+		data.Aliases[kind] = map[string]int{}
+	}
+
+	for _, migration := range migrations {
+		if err := checkSeeds(migration, kinds); err != nil {
+			return nil, err
+		}
+
+		for _, match := range seedName.FindAllStringSubmatch(migration.Up, -1) {
+			kind, ok := kinds[match[1]]
+			if !ok {
+				continue
+			}
+
+			id, err := strconv.Atoi(match[2])
+			if err != nil {
+				return nil, fmt.Errorf("migration %04d_%s: invalid id %q", migration.Version, migration.Name, match[2])
+			}
+
+			data.Names[kind][internal.NormalizeName(unquote(match[3]))] = id
+		}
+
+		for _, match := range seedAlias.FindAllStringSubmatch(migration.Up, -1) {
+			aliases, ok := data.Aliases[match[1]]
+			if !ok {
+				return nil, fmt.Errorf("migration %04d_%s: unknown alias kind %q", migration.Version, migration.Name, match[1])
+			}
+
+			id, err := strconv.Atoi(match[3])
+			if err != nil {
+				return nil, fmt.Errorf("migration %04d_%s: invalid id %q", migration.Version, migration.Name, match[3])
+			}
+
+			aliases[internal.NormalizeName(unquote(match[2]))] = id
+		}
+	}
+
+	return data, nil
+}
+
+// checkSeeds fails on the statements changing a reference table that are not a seed replayed by
+// replaySeeds: updates, deletes, inserts of several rows, renames...
+func checkSeeds(migration Migration, kinds map[string]string) error {
+	for _, match := range seedChange.FindAllStringSubmatchIndex(migration.Up, -1) {
+		table := strings.ToLower(migration.Up[match[4]:match[5]])
+		if _, ok := kinds[table]; !ok && table != "aliases" {
+			continue
+		}
+
+		line, _, _ := strings.Cut(migration.Up[match[0]:], "\n")
+		line = strings.TrimSpace(line)
+		if seedName.MatchString(line) || seedAlias.MatchString(line) {
+			continue
+		}
+
+		return fmt.Errorf("migration %04d_%s: the reference data cannot replay %q, replay it in migrations/reference.go", migration.Version, migration.Name, line)
+	}
+
+	return nil
+}
+
+// unquote reverts the doubled quotes of a SQL string literal.
+func unquote(literal string) string {
+	return strings.ReplaceAll(literal, "''", "'")
+}
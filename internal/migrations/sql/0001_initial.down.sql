@@ -0,0 +1,9 @@
+-- Reverts 0001_initial.up.sql, dropping every table and view.
+DROP VIEW IF EXISTS VISTA_INDICADORES;
+DROP VIEW IF EXISTS VISTA_CONSOLIDADO;
+
+DROP TABLE IF EXISTS population;
+DROP TABLE IF EXISTS entries;
+DROP TABLE IF EXISTS districts;
+DROP TABLE IF EXISTS insurers;
+DROP TABLE IF EXISTS gender;
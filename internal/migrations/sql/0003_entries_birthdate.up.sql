@@ -0,0 +1,2 @@
+-- Keeps the birthdate of every entry so ages can be recomputed, rows imported before stay NULL.
+ALTER TABLE entries ADD COLUMN birthdate DATE;
@@ -0,0 +1,76 @@
+-- Restores VISTA_INDICADORES without the gender dimension.
+DROP VIEW VISTA_INDICADORES;
+
+CREATE VIEW VISTA_INDICADORES AS
+
+-- Entries per Age-Zone
+WITH epa AS (
+    SELECT 
+        EXTRACT(YEAR FROM creation_date) AS year,
+        age,
+        district_id,
+        COUNT(*) count
+    FROM entries
+    GROUP BY year, age, district_id
+),
+
+-- Population per Age-Zone
+ppa AS (
+    SELECT 
+        year, 
+        age, 
+        district_id, 
+        SUM(population) count
+    FROM population
+    GROUP BY year, age, district_id
+    ORDER BY year, age, district_id
+),
+
+-- Joined data
+records AS (
+    SELECT
+        epa.year AS year,
+        epa.age AS age,
+        epa.district_id AS district_id,
+        epa.count AS entries,
+        ppa.count AS population
+    FROM epa INNER JOIN ppa 
+        ON epa.age = ppa.age 
+        AND epa.district_id = ppa.district_id
+        AND epa.year = ppa.year
+    ORDER BY district_id, age, year
+)
+
+SELECT
+    year,
+    (SELECT CONCAT(id, '- ', name) FROM districts WHERE id = district_id) district,
+    CASE
+        WHEN age BETWEEN 0 AND 4 THEN '0-4'
+        WHEN age BETWEEN 5 AND 9 THEN '5-9'
+        WHEN age BETWEEN 10 AND 14 THEN '10-14'
+        WHEN age BETWEEN 15 AND 19 THEN '15-19'
+        WHEN age BETWEEN 20 AND 24 THEN '20-24'
+        WHEN age BETWEEN 25 AND 29 THEN '25-29'
+        WHEN age BETWEEN 30 AND 34 THEN '30-34'
+        WHEN age BETWEEN 35 AND 39 THEN '35-39'
+        WHEN age BETWEEN 40 AND 44 THEN '40-44'
+        WHEN age BETWEEN 45 AND 49 THEN '45-49'
+        WHEN age BETWEEN 50 AND 54 THEN '50-54'
+        WHEN age BETWEEN 55 AND 59 THEN '55-59'
+        WHEN age BETWEEN 60 AND 64 THEN '60-64'
+        WHEN age BETWEEN 65 AND 69 THEN '65-69'
+        WHEN age BETWEEN 70 AND 74 THEN '70-74'
+        WHEN age BETWEEN 75 AND 79 THEN '75-79'
+        WHEN age BETWEEN 80 AND 84 THEN '80-84'
+        WHEN age BETWEEN 85 AND 89 THEN '85-89'
+        WHEN age BETWEEN 90 AND 94 THEN '90-94'
+        WHEN age BETWEEN 95 AND 99 THEN '95-99'
+    END AS age_range,
+    SUM(entries) entries,
+    SUM(population) population,
+    (SUM(entries) * 100.0 / SUM(population)) percentage
+FROM records
+GROUP BY year, age_range, district_id
+ORDER BY year, age_range, district_id;
+
+ALTER TABLE population DROP COLUMN gender_id;
@@ -0,0 +1,106 @@
+-- Keeps the course of life and age group classifications of the population extract,
+-- and derives the age ranges of VISTA_INDICADORES from age_groups instead of a CASE.
+CREATE TABLE life_courses (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    min_age INTEGER NOT NULL,
+    max_age INTEGER NOT NULL
+);
+
+CREATE TABLE age_groups (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    min_age INTEGER NOT NULL,
+    max_age INTEGER NOT NULL
+);
+
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (1, 'Primera infancia', 0, 5);
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (2, 'Infancia', 6, 11);
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (3, 'Adolescencia', 12, 17);
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (4, 'Juventud', 18, 28);
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (5, 'Adultez', 29, 59);
+INSERT INTO life_courses (id, name, min_age, max_age) VALUES (6, 'Vejez', 60, 120);
+
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (1, '00 a 04', 0, 4);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (2, '05 a 09', 5, 9);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (3, '10 a 14', 10, 14);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (4, '15 a 19', 15, 19);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (5, '20 a 24', 20, 24);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (6, '25 a 29', 25, 29);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (7, '30 a 34', 30, 34);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (8, '35 a 39', 35, 39);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (9, '40 a 44', 40, 44);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (10, '45 a 49', 45, 49);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (11, '50 a 54', 50, 54);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (12, '55 a 59', 55, 59);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (13, '60 a 64', 60, 64);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (14, '65 a 69', 65, 69);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (15, '70 a 74', 70, 74);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (16, '75 a 79', 75, 79);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (17, '80 a 84', 80, 84);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (18, '85 a 89', 85, 89);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (19, '90 a 94', 90, 94);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (20, '95 a 99', 95, 99);
+INSERT INTO age_groups (id, name, min_age, max_age) VALUES (21, '100 o más', 100, 120);
+
+ALTER TABLE population ADD COLUMN life_course_id INTEGER REFERENCES life_courses(id);
+ALTER TABLE population ADD COLUMN age_group_id INTEGER REFERENCES age_groups(id);
+
+DROP VIEW VISTA_INDICADORES;
+
+-- Entries and population per year, district, gender and single age, banded by the reports
+CREATE VIEW VISTA_INDICADORES_EDAD AS
+
+-- Entries per Age-Zone-Gender
+WITH epa AS (
+    SELECT 
+        EXTRACT(YEAR FROM creation_date) AS year,
+        age,
+        district_id,
+        gender_id,
+        COUNT(*) count
+    FROM entries
+    GROUP BY year, age, district_id, gender_id
+),
+
+-- Population per Age-Zone-Gender
+ppa AS (
+    SELECT 
+        year, 
+        age, 
+        district_id, 
+        gender_id,
+        SUM(population) count
+    FROM population
+    WHERE gender_id IS NOT NULL
+    GROUP BY year, age, district_id, gender_id
+)
+
+SELECT
+    epa.year AS year,
+    epa.age AS age,
+    epa.district_id AS district_id,
+    epa.gender_id AS gender_id,
+    epa.count AS entries,
+    ppa.count AS population
+FROM epa INNER JOIN ppa 
+    ON epa.age = ppa.age 
+    AND epa.district_id = ppa.district_id
+    AND epa.year = ppa.year
+    AND epa.gender_id = ppa.gender_id;
+
+CREATE VIEW VISTA_INDICADORES AS
+SELECT
+    r.year,
+    CONCAT(d.id, '- ', d.name) district,
+    g.name gender,
+    a.name age_range,
+    SUM(r.entries) entries,
+    SUM(r.population) population,
+    (SUM(r.entries) * 100.0 / SUM(r.population)) percentage
+FROM VISTA_INDICADORES_EDAD r
+INNER JOIN age_groups a ON r.age BETWEEN a.min_age AND a.max_age
+INNER JOIN districts d ON r.district_id = d.id
+INNER JOIN gender g ON r.gender_id = g.id
+GROUP BY r.year, a.id, a.name, d.id, d.name, g.id, g.name
+ORDER BY r.year, a.id, d.id, g.id;
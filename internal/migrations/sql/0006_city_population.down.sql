@@ -0,0 +1,5 @@
+DROP VIEW VISTA_CONSISTENCIA_POBLACION;
+
+UPDATE population SET district_id = 99 WHERE district_id = 0;
+
+DELETE FROM districts WHERE id = 0;
@@ -0,0 +1,48 @@
+-- Gives the city-wide rows of the population extract (CODIGO_LOCALIDAD 0) their own
+-- geography instead of the Unknown district, and lists the city totals that do not
+-- match the sum of their districts.
+INSERT INTO districts (id, name) VALUES (0, 'Bogota');
+
+UPDATE population SET district_id = 0 WHERE district_id = 99;
+
+CREATE VIEW VISTA_CONSISTENCIA_POBLACION AS
+
+-- City-wide population per Age-Gender
+WITH city AS (
+    SELECT
+        year,
+        age,
+        gender_id,
+        SUM(population) total
+    FROM population
+    WHERE district_id = 0
+    GROUP BY year, age, gender_id
+),
+
+-- Population of every district per Age-Gender
+zones AS (
+    SELECT
+        year,
+        age,
+        gender_id,
+        SUM(population) total
+    FROM population
+    WHERE district_id NOT IN (0, 99)
+    GROUP BY year, age, gender_id
+)
+
+SELECT
+    city.year,
+    city.age,
+    g.name gender,
+    city.total city,
+    COALESCE(zones.total, 0) districts,
+    COALESCE(zones.total, 0) - city.total difference
+FROM city
+LEFT JOIN zones
+    ON city.year = zones.year
+    AND city.age = zones.age
+    AND city.gender_id IS NOT DISTINCT FROM zones.gender_id
+LEFT JOIN gender g ON city.gender_id = g.id
+WHERE city.total IS DISTINCT FROM zones.total
+ORDER BY city.year, city.age, city.gender_id;
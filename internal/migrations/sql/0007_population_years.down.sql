@@ -0,0 +1,42 @@
+-- Joins the entries only against the years with a population extract again.
+CREATE OR REPLACE VIEW VISTA_INDICADORES_EDAD AS
+
+-- Entries per Age-Zone-Gender
+WITH epa AS (
+    SELECT 
+        EXTRACT(YEAR FROM creation_date) AS year,
+        age,
+        district_id,
+        gender_id,
+        COUNT(*) count
+    FROM entries
+    GROUP BY year, age, district_id, gender_id
+),
+
+-- Population per Age-Zone-Gender
+ppa AS (
+    SELECT 
+        year, 
+        age, 
+        district_id, 
+        gender_id,
+        SUM(population) count
+    FROM population
+    WHERE gender_id IS NOT NULL
+    GROUP BY year, age, district_id, gender_id
+)
+
+SELECT
+    epa.year AS year,
+    epa.age AS age,
+    epa.district_id AS district_id,
+    epa.gender_id AS gender_id,
+    epa.count AS entries,
+    ppa.count AS population
+FROM epa INNER JOIN ppa 
+    ON epa.age = ppa.age 
+    AND epa.district_id = ppa.district_id
+    AND epa.year = ppa.year
+    AND epa.gender_id = ppa.gender_id;
+
+DROP VIEW VISTA_POBLACION_ANUAL;
@@ -0,0 +1,123 @@
+-- Fills in the population of the years with entries but no extract: years between two
+-- extracts are interpolated linearly, years before the first or after the last carry the
+-- nearest extract. The closest extracts are found with window functions over the grid of
+-- years and cells, so the view stays linear in the size of the grid.
+CREATE VIEW VISTA_POBLACION_ANUAL AS
+
+-- Loaded population per Year-Age-Zone-Gender
+WITH series AS (
+    SELECT
+        year,
+        age,
+        district_id,
+        gender_id,
+        SUM(population) population
+    FROM population
+    WHERE gender_id IS NOT NULL
+    GROUP BY year, age, district_id, gender_id
+),
+
+-- Every Age-Zone-Gender with population
+cells AS (
+    SELECT DISTINCT age, district_id, gender_id FROM series
+),
+
+-- Every year with population or entries, a stray entry date only adds its own year
+years AS (
+    SELECT year FROM series
+    UNION
+    SELECT EXTRACT(YEAR FROM creation_date)::INTEGER FROM entries
+),
+
+-- Every Year-Age-Zone-Gender, numbering the extracts seen up to each year in both directions
+grid AS (
+    SELECT
+        y.year,
+        c.age,
+        c.district_id,
+        c.gender_id,
+        loaded.year loaded_year,
+        loaded.population,
+        COUNT(loaded.year) OVER (PARTITION BY c.age, c.district_id, c.gender_id ORDER BY y.year) prev_extract,
+        COUNT(loaded.year) OVER (PARTITION BY c.age, c.district_id, c.gender_id ORDER BY y.year DESC) next_extract
+    FROM years y
+    CROSS JOIN cells c
+    LEFT JOIN series loaded
+        ON loaded.year = y.year
+        AND loaded.age = c.age
+        AND loaded.district_id = c.district_id
+        AND loaded.gender_id = c.gender_id
+),
+
+-- The rows sharing a number follow the same extract, the only one loaded among them
+nearest AS (
+    SELECT
+        year,
+        age,
+        district_id,
+        gender_id,
+        loaded_year,
+        population,
+        MAX(loaded_year) OVER (PARTITION BY age, district_id, gender_id, prev_extract) prev_year,
+        MAX(population) OVER (PARTITION BY age, district_id, gender_id, prev_extract) prev_population,
+        MAX(loaded_year) OVER (PARTITION BY age, district_id, gender_id, next_extract) next_year,
+        MAX(population) OVER (PARTITION BY age, district_id, gender_id, next_extract) next_population
+    FROM grid
+)
+
+SELECT
+    year,
+    age,
+    district_id,
+    gender_id,
+    CASE
+        WHEN loaded_year IS NOT NULL THEN population
+        WHEN prev_year IS NOT NULL AND next_year IS NOT NULL THEN
+            ROUND(prev_population + (next_population - prev_population) * (year - prev_year)::NUMERIC / (next_year - prev_year))::BIGINT
+        ELSE COALESCE(prev_population, next_population)
+    END AS population,
+    CASE
+        WHEN loaded_year IS NOT NULL THEN 'loaded'
+        WHEN prev_year IS NOT NULL AND next_year IS NOT NULL THEN 'interpolated'
+        ELSE 'carried'
+    END AS source
+FROM nearest;
+
+-- Joins the entries of every year against the filled in population
+CREATE OR REPLACE VIEW VISTA_INDICADORES_EDAD AS
+
+-- Entries per Age-Zone-Gender
+WITH epa AS (
+    SELECT 
+        EXTRACT(YEAR FROM creation_date) AS year,
+        age,
+        district_id,
+        gender_id,
+        COUNT(*) count
+    FROM entries
+    GROUP BY year, age, district_id, gender_id
+),
+
+-- Population per Age-Zone-Gender, filled in for the years without an extract
+ppa AS (
+    SELECT 
+        year, 
+        age, 
+        district_id, 
+        gender_id,
+        population count
+    FROM VISTA_POBLACION_ANUAL
+)
+
+SELECT
+    epa.year AS year,
+    epa.age AS age,
+    epa.district_id AS district_id,
+    epa.gender_id AS gender_id,
+    epa.count AS entries,
+    ppa.count AS population
+FROM epa INNER JOIN ppa 
+    ON epa.age = ppa.age 
+    AND epa.district_id = ppa.district_id
+    AND epa.year = ppa.year
+    AND epa.gender_id = ppa.gender_id;
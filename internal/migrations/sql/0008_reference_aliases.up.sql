@@ -0,0 +1,21 @@
+-- Aliases of the reference data, loaded along with the districts, insurers and gender
+-- tables when importing so a new spelling is a data change. Aliases are stored
+-- normalized: upper case, without accents or punctuation.
+CREATE TABLE aliases (
+    kind TEXT NOT NULL CHECK (kind IN ('district', 'insurer', 'gender')),
+    alias TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    PRIMARY KEY (kind, alias)
+);
+
+INSERT INTO aliases (kind, alias, target_id) VALUES ('district', 'SANTAFE', 3);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('district', 'MARTIRES', 14);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('district', 'CANDELARIA', 17);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('district', 'RAFAEL URIBE URIBE', 18);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'HOMBRE', 0);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'HOMBRES', 0);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'MASCULINO', 0);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'MUJER', 1);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'MUJERES', 1);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'FEMENINO', 1);
+INSERT INTO aliases (kind, alias, target_id) VALUES ('gender', 'INTERSEXUAL', 2);
@@ -0,0 +1,7 @@
+-- Reverts 0009_import_runs.up.sql, dropping the ledger along with the runs of every row.
+DROP TABLE rejects;
+
+ALTER TABLE population DROP COLUMN run_id;
+ALTER TABLE entries DROP COLUMN run_id;
+
+DROP TABLE import_runs;
@@ -0,0 +1,32 @@
+-- Adds the import_runs ledger, the rejects table and the run_id columns.
+CREATE TABLE import_runs (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    program INTEGER NOT NULL DEFAULT 0,
+    source_file TEXT NOT NULL,
+    file_hash CHAR(64) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    stored_rows INTEGER NOT NULL DEFAULT 0,
+    rejected_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX import_runs_file_hash_idx ON import_runs (kind, program, file_hash);
+
+ALTER TABLE entries ADD COLUMN run_id INTEGER REFERENCES import_runs(id);
+CREATE INDEX entries_run_id_idx ON entries (run_id);
+
+ALTER TABLE population ADD COLUMN run_id INTEGER REFERENCES import_runs(id);
+CREATE INDEX population_run_id_idx ON population (run_id);
+
+CREATE TABLE rejects (
+    id SERIAL PRIMARY KEY,
+    source_file TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    reason VARCHAR(64) NOT NULL,
+    error TEXT NOT NULL,
+    record TEXT[],
+    run_id INTEGER REFERENCES import_runs(id)
+);
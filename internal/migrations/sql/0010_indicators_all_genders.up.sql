@@ -0,0 +1,98 @@
+-- Adds an all-genders total to the indicators. Population is only split into Male and
+-- Female, so the entries of the other genders (Non Binary, Other and Unknown, all of
+-- program 4 for instance) have no population of their own and only count in the total
+-- rows, which have a NULL gender_id and are labelled 'Total' in VISTA_INDICADORES.
+CREATE OR REPLACE VIEW VISTA_INDICADORES_EDAD AS
+
+-- Entries per Age-Zone-Gender
+WITH epa AS (
+    SELECT 
+        EXTRACT(YEAR FROM creation_date) AS year,
+        age,
+        district_id,
+        gender_id,
+        COUNT(*) count
+    FROM entries
+    GROUP BY year, age, district_id, gender_id
+),
+
+-- Population per Age-Zone-Gender, filled in for the years without an extract
+ppa AS (
+    SELECT 
+        year, 
+        age, 
+        district_id, 
+        gender_id,
+        population count
+    FROM VISTA_POBLACION_ANUAL
+),
+
+-- Entries per Age-Zone of every gender
+eta AS (
+    SELECT
+        year,
+        age,
+        district_id,
+        SUM(count)::BIGINT count
+    FROM epa
+    GROUP BY year, age, district_id
+),
+
+-- Population per Age-Zone of both sexes
+pta AS (
+    SELECT
+        year,
+        age,
+        district_id,
+        SUM(count)::BIGINT count
+    FROM ppa
+    GROUP BY year, age, district_id
+)
+
+-- Per gender, only the genders with population
+SELECT
+    epa.year AS year,
+    epa.age AS age,
+    epa.district_id AS district_id,
+    epa.gender_id AS gender_id,
+    epa.count AS entries,
+    ppa.count AS population
+FROM epa INNER JOIN ppa 
+    ON epa.age = ppa.age 
+    AND epa.district_id = ppa.district_id
+    AND epa.year = ppa.year
+    AND epa.gender_id = ppa.gender_id
+
+UNION ALL
+
+-- Every gender together
+SELECT
+    eta.year AS year,
+    eta.age AS age,
+    eta.district_id AS district_id,
+    NULL::INTEGER AS gender_id,
+    eta.count AS entries,
+    pta.count AS population
+FROM eta INNER JOIN pta
+    ON eta.age = pta.age
+    AND eta.district_id = pta.district_id
+    AND eta.year = pta.year;
+
+-- the gender column changes type, so the view has to be recreated
+DROP VIEW VISTA_INDICADORES;
+
+CREATE VIEW VISTA_INDICADORES AS
+SELECT
+    r.year,
+    CONCAT(d.id, '- ', d.name) district,
+    COALESCE(g.name, 'Total') gender,
+    a.name age_range,
+    SUM(r.entries) entries,
+    SUM(r.population) population,
+    (SUM(r.entries) * 100.0 / SUM(r.population)) percentage
+FROM VISTA_INDICADORES_EDAD r
+INNER JOIN age_groups a ON r.age BETWEEN a.min_age AND a.max_age
+INNER JOIN districts d ON r.district_id = d.id
+LEFT JOIN gender g ON r.gender_id = g.id
+GROUP BY r.year, a.id, a.name, d.id, d.name, g.id, g.name
+ORDER BY r.year, a.id, d.id, g.id NULLS LAST;
@@ -0,0 +1,337 @@
+package parsers
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+//go:embed mappings/*.json
+var builtinMappings embed.FS
+
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+	MatchDistrict = "district"
+	MatchInsurer  = "insurer"
+	MatchGender   = "gender"
+)
+
+// ProgramMapping describes how the records of a program extract map onto a ProgramEntry.
+type ProgramMapping struct {
+	Program   int           `json:"program"`
+	Delimiter string        `json:"delimiter"`
+	Header    bool          `json:"header"`
+	Columns   ColumnMapping `json:"columns"`
+	Headers   HeaderMapping `json:"headers"`
+	Layouts   LayoutMapping `json:"layouts"`
+	Districts ValueMapping  `json:"districts"`
+	Insurers  ValueMapping  `json:"insurers"`
+	Sexes     ValueMapping  `json:"sexes"`
+}
+
+// ColumnMapping holds the index of every column, a missing column resolves through the value defaults.
+type ColumnMapping struct {
+	District  *int `json:"district"`
+	Insurer   *int `json:"insurer"`
+	Sex       *int `json:"sex"`
+	Birthdate *int `json:"birthdate"`
+	Date      *int `json:"date"`
+}
+
+// HeaderMapping holds the accepted names of every column, resolved against the header row.
+// A column found by name takes precedence over its index, a column with names and no index
+// is required to be in the header.
+type HeaderMapping struct {
+	District  []string `json:"district"`
+	Insurer   []string `json:"insurer"`
+	Sex       []string `json:"sex"`
+	Birthdate []string `json:"birthdate"`
+	Date      []string `json:"date"`
+}
+
+// named reports whether any column is resolved by name.
+func (h *HeaderMapping) named() bool {
+	return len(h.District)+len(h.Insurer)+len(h.Sex)+len(h.Birthdate)+len(h.Date) > 0
+}
+
+// LayoutMapping holds the accepted time layouts of the date columns, tried in order.
+type LayoutMapping struct {
+	Date      []string `json:"date"`
+	Birthdate []string `json:"birthdate"`
+}
+
+// ValueMapping is a dictionary from raw column values to reference ids. The district match
+// goes through the shared district resolver, with the values as extra aliases and fuzzy as
+// the similarity threshold of edit distance matches. The insurer match goes through the
+// shared insurer resolver, with the values as contains rules tried longest first. The gender
+// match looks up the normalized value in the gender names and aliases, the values first.
+// The prefix and contains matches try the values longest first, then alphabetically.
+type ValueMapping struct {
+	Match     string         `json:"match"`
+	FoldCase  bool           `json:"fold_case"`
+	Values    map[string]int `json:"values"`
+	Empty     *int           `json:"empty"`
+	Default   *int           `json:"default"`
+	Warn      bool           `json:"warn"`
+	Fuzzy     float64        `json:"fuzzy"`
+	districts *internal.DistrictResolver
+	insurers  *internal.InsurerResolver
+	genders   map[string]int
+	ordered   []string
+}
+
+// LoadProgramMapping reads a mapping file from disk.
+func LoadProgramMapping(path string) (*ProgramMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeProgramMapping(data)
+}
+
+// BuiltinProgramMapping returns the mapping shipped with the binary for the given program.
+func BuiltinProgramMapping(program int) (*ProgramMapping, error) {
+	data, err := builtinMappings.ReadFile(fmt.Sprintf("mappings/program-%d.json", program))
+	if err != nil {
+		return nil, errors.New("program not supported")
+	}
+
+	return decodeProgramMapping(data)
+}
+
+func decodeProgramMapping(data []byte) (*ProgramMapping, error) {
+	var mapping ProgramMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to decode mapping: %w", err)
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &mapping, nil
+}
+
+// Validate checks that the mapping can actually produce entries.
+func (m *ProgramMapping) Validate() error {
+	if m.Program <= 0 {
+		return errors.New("mapping: program must be a positive number")
+	}
+
+	if utf8.RuneCountInString(m.Delimiter) > 1 {
+		return fmt.Errorf("mapping: delimiter %q must be a single character", m.Delimiter)
+	}
+
+	if (m.Columns.Date == nil && len(m.Headers.Date) == 0) || (m.Columns.Birthdate == nil && len(m.Headers.Birthdate) == 0) {
+		return errors.New("mapping: date and birthdate columns are required")
+	}
+
+	if !m.Header && m.Headers.named() {
+		return errors.New("mapping: column names require a header row")
+	}
+
+	if len(m.Layouts.Date) == 0 || len(m.Layouts.Birthdate) == 0 {
+		return errors.New("mapping: date and birthdate layouts are required")
+	}
+
+	for name, values := range map[string]ValueMapping{"districts": m.Districts, "insurers": m.Insurers, "sexes": m.Sexes} {
+		switch values.Match {
+		case "", MatchExact, MatchPrefix, MatchContains:
+		case MatchDistrict:
+			if name != "districts" {
+				return fmt.Errorf("mapping: the district match only applies to districts, not %s", name)
+			}
+		case MatchInsurer:
+			if name != "insurers" {
+				return fmt.Errorf("mapping: the insurer match only applies to insurers, not %s", name)
+			}
+		case MatchGender:
+			if name != "sexes" {
+				return fmt.Errorf("mapping: the gender match only applies to sexes, not %s", name)
+			}
+		default:
+			return fmt.Errorf("mapping: unknown match %q for %s", values.Match, name)
+		}
+
+		if values.Fuzzy < 0 || values.Fuzzy > 1 {
+			return fmt.Errorf("mapping: fuzzy threshold of %s must be between 0 and 1", name)
+		}
+	}
+
+	return nil
+}
+
+// Bind resolves the district, insurer and gender values through the names and aliases of the
+// reference data and orders the prefix and contains values. It must be called before Resolve.
+func (m *ProgramMapping) Bind(reference *internal.ReferenceData) {
+	for _, values := range []*ValueMapping{&m.Districts, &m.Insurers, &m.Sexes} {
+		if values.Match == MatchPrefix || values.Match == MatchContains {
+			values.ordered = orderedValues(values.Values)
+		}
+	}
+
+	if m.Districts.Match == MatchDistrict {
+		// entries belong to a district, the city and unknown geographies are only reachable through values
+		aliases := map[string]int{}
+		for name, id := range reference.Lookup(internal.ReferenceDistrict) {
+			if internal.IsDistrict(id) {
+				aliases[name] = id
+			}
+		}
+
+		maps.Copy(aliases, m.Districts.Values)
+		m.Districts.districts = internal.NewDistrictResolver(aliases, m.Districts.Fuzzy)
+	}
+
+	if m.Insurers.Match == MatchInsurer {
+		m.Insurers.insurers = internal.NewInsurerResolver(reference.Lookup(internal.ReferenceInsurer), m.Insurers.Values)
+	}
+
+	if m.Sexes.Match == MatchGender {
+		genders := reference.Lookup(internal.ReferenceGender)
+		for value, id := range m.Sexes.Values {
+			genders[internal.NormalizeName(value)] = id
+		}
+
+		m.Sexes.genders = genders
+	}
+}
+
+// ResolveColumns resolves the named columns against a header row, falling back on their index
+// when none of the names is present. The other columns keep their index.
+func (m *ProgramMapping) ResolveColumns(header []string) (ColumnMapping, error) {
+	resolved := m.Columns
+	names := headerColumns(header)
+
+	for _, column := range []struct {
+		kind    string
+		aliases []string
+		index   **int
+	}{
+		{"district", m.Headers.District, &resolved.District},
+		{"insurer", m.Headers.Insurer, &resolved.Insurer},
+		{"gender", m.Headers.Sex, &resolved.Sex},
+		{"birthdate", m.Headers.Birthdate, &resolved.Birthdate},
+		{"date", m.Headers.Date, &resolved.Date},
+	} {
+		if len(column.aliases) == 0 {
+			continue
+		}
+
+		index, err := findColumn(names, column.kind, column.aliases)
+		if err == nil {
+			*column.index = &index
+		} else if *column.index == nil {
+			return resolved, err
+		}
+	}
+
+	return resolved, nil
+}
+
+// Comma returns the delimiter as a rune, defaulting to a comma.
+func (m *ProgramMapping) Comma() rune {
+	if m.Delimiter == "" {
+		return ','
+	}
+
+	r, _ := utf8.DecodeRuneInString(m.Delimiter)
+	return r
+}
+
+// Resolve maps a raw value onto its reference id.
+func (v *ValueMapping) Resolve(kind string, value string) (int, error) {
+	if value == "" && v.Empty != nil {
+		return *v.Empty, nil
+	}
+
+	key := value
+	if v.FoldCase {
+		key = strings.ToUpper(key)
+	}
+
+	switch v.Match {
+	case MatchDistrict:
+		if id, err := v.districts.Resolve(value); err == nil {
+			return id, nil
+		}
+	case MatchInsurer:
+		if id, ok := v.insurers.Resolve(value); ok {
+			return id, nil
+		}
+	case MatchGender:
+		if id, ok := v.genders[internal.NormalizeName(value)]; ok {
+			return id, nil
+		}
+	case MatchPrefix:
+		for _, prefix := range v.ordered {
+			if strings.HasPrefix(key, prefix) {
+				return v.Values[prefix], nil
+			}
+		}
+	case MatchContains:
+		for _, substr := range v.ordered {
+			if strings.Contains(key, substr) {
+				return v.Values[substr], nil
+			}
+		}
+	default:
+		if id, ok := v.Values[key]; ok {
+			return id, nil
+		}
+	}
+
+	if v.Default == nil {
+		return 0, fmt.Errorf("unknown %s %q", kind, value)
+	}
+
+	if v.Warn {
+		log.Printf("unknown %s: %q - defaulting to %d", kind, value, *v.Default)
+	}
+
+	return *v.Default, nil
+}
+
+// orderedValues returns the values longest first, so a value overlapping a shorter one wins
+// regardless of map iteration.
+func orderedValues(values map[string]int) []string {
+	ordered := slices.Collect(maps.Keys(values))
+	sort.Slice(ordered, func(i, j int) bool {
+		if len(ordered[i]) != len(ordered[j]) {
+			return len(ordered[i]) > len(ordered[j])
+		}
+
+		return ordered[i] < ordered[j]
+	})
+
+	return ordered
+}
+
+// parseDate parses the date part of a value with the first layout that accepts it.
+func parseDate(value string, layouts []string) (time.Time, error) {
+	value = strings.Split(value, " ")[0]
+
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		if date, err = time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, err
+}
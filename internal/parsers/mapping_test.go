@@ -0,0 +1,142 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+func TestValueMappingResolveLongestFirst(t *testing.T) {
+	values := map[string]int{
+		"SALUD":        1,
+		"SALUD TOTAL":  2,
+		"SALUD TOTALE": 3,
+		"NUEVA":        4,
+		"NUEVA EPS":    5,
+	}
+
+	tests := []struct {
+		match string
+		value string
+		want  int
+	}{
+		{MatchPrefix, "SALUD TOTAL EPS", 2},
+		{MatchPrefix, "SALUD TOTALES", 3},
+		{MatchPrefix, "SALUDCOOP", 1},
+		{MatchPrefix, "NUEVA EPS S.A.", 5},
+		{MatchContains, "EPS SALUD TOTAL S.A.", 2},
+		{MatchContains, "LA NUEVA EPS", 5},
+		{MatchContains, "CAJA DE SALUD", 1},
+	}
+
+	for _, tt := range tests {
+		mapping := &ProgramMapping{Insurers: ValueMapping{Match: tt.match, Values: values}}
+		mapping.Bind(&internal.ReferenceData{})
+
+		// map iteration changes from run to run, resolve enough times to notice
+		for i := 0; i < 50; i++ {
+			got, err := mapping.Insurers.Resolve("insurer", tt.value)
+			if err != nil {
+				t.Fatalf("%s %q: %v", tt.match, tt.value, err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("%s %q = %d, want %d", tt.match, tt.value, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestValueMappingResolveDefault(t *testing.T) {
+	fallback := 9
+	mapping := &ProgramMapping{Insurers: ValueMapping{Match: MatchPrefix, Values: map[string]int{"SALUD": 1}, Default: &fallback}}
+	mapping.Bind(&internal.ReferenceData{})
+
+	if got, err := mapping.Insurers.Resolve("insurer", "COMPENSAR"); err != nil || got != fallback {
+		t.Errorf("Resolve(COMPENSAR) = %d, %v, want %d", got, err, fallback)
+	}
+
+	mapping.Insurers.Default = nil
+	if _, err := mapping.Insurers.Resolve("insurer", "COMPENSAR"); err == nil {
+		t.Error("Resolve(COMPENSAR) without a default did not fail")
+	}
+}
+
+func TestBuiltinProgramMappings(t *testing.T) {
+	for program := 1; program <= 4; program++ {
+		if _, err := BuiltinProgramMapping(program); err != nil {
+			t.Errorf("program %d: %v", program, err)
+		}
+	}
+
+	if _, err := BuiltinProgramMapping(5); err == nil {
+		t.Error("program 5 has no mapping but loaded")
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	index := func(i int) *int { return &i }
+
+	mapping := &ProgramMapping{
+		Columns: ColumnMapping{District: index(1), Birthdate: index(3), Date: index(5)},
+		Headers: HeaderMapping{
+			District:  []string{"LOCALIDAD"},
+			Sex:       []string{"SEXO", "GENERO"},
+			Birthdate: []string{"FECHA_NACIMIENTO"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		header []string
+		want   ColumnMapping
+		err    bool
+	}{
+		{
+			name:   "names found",
+			header: []string{"\ufeffFecha_Nacimiento ", "Localidad", "x", "y", "Género", "z"},
+			want:   ColumnMapping{District: index(1), Sex: index(4), Birthdate: index(0), Date: index(5)},
+		},
+		{
+			name:   "indexes as fallback",
+			header: []string{"ID", "DISTRITO", "ASEG", "NAC", "SEXO", "F"},
+			want:   ColumnMapping{District: index(1), Sex: index(4), Birthdate: index(3), Date: index(5)},
+		},
+		{
+			name:   "named column without index missing",
+			header: []string{"ID", "DISTRITO", "ASEG", "NAC", "GEN", "F"},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := mapping.ResolveColumns(tt.header)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: resolved %v, want an error", tt.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		for _, column := range []struct {
+			kind      string
+			got, want *int
+		}{
+			{"district", got.District, tt.want.District},
+			{"insurer", got.Insurer, tt.want.Insurer},
+			{"sex", got.Sex, tt.want.Sex},
+			{"birthdate", got.Birthdate, tt.want.Birthdate},
+			{"date", got.Date, tt.want.Date},
+		} {
+			if (column.got == nil) != (column.want == nil) || (column.got != nil && *column.got != *column.want) {
+				t.Errorf("%s: %s column = %v, want %v", tt.name, column.kind, column.got, column.want)
+			}
+		}
+	}
+}
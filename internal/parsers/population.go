@@ -1,44 +1,99 @@
 package parsers
 
 import (
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/foxinuni/prueba-patrones/internal"
 )
 
+// populationHeaders are the accepted names of the population columns.
+var populationHeaders = struct {
+	Year       []string
+	District   []string
+	Sex        []string
+	Age        []string
+	LifeCourse []string
+	AgeGroup   []string
+	Population []string
+}{
+	Year:       []string{"ANO", "AÑO", "YEAR"},
+	District:   []string{"CODIGO_LOCALIDAD", "COD_LOCALIDAD", "LOCALIDAD"},
+	Sex:        []string{"SEXO", "GENERO"},
+	Age:        []string{"EDAD", "AGE"},
+	LifeCourse: []string{"CURSODEVIDA", "CURSO_DE_VIDA"},
+	AgeGroup:   []string{"GRUPOEDAD", "GRUPO_EDAD"},
+	Population: []string{"POBLACION", "POBLACION_7", "TOTAL"},
+}
+
+// populationColumns holds the index of every population column, the classifications are optional (-1 when missing).
+type populationColumns struct {
+	year       int
+	district   int
+	sex        int
+	age        int
+	lifeCourse int
+	ageGroup   int
+	population int
+}
+
 type PopulationParser struct {
-	wg sync.WaitGroup
+	skipCity bool
+	sexes    map[string]int
+	columns  populationColumns
+	read     atomic.Int64
+	wg       sync.WaitGroup
 }
 
-func NewPopulationParser() internal.EntryParser[internal.PopulationEntry] {
-	return &PopulationParser{}
+// NewPopulationParser creates a parser for population extracts resolving the sex column through reference,
+// skipCity drops the city-wide aggregate rows.
+func NewPopulationParser(skipCity bool, reference *internal.ReferenceData) internal.EntryParser[internal.PopulationEntry] {
+	return &PopulationParser{
+		skipCity: skipCity,
+		sexes:    reference.Lookup(internal.ReferenceGender),
+	}
 }
 
-func (p *PopulationParser) ParseFile(path string) (<-chan internal.PopulationEntry, error) {
+func (p *PopulationParser) ParseFile(ctx context.Context, path string) (<-chan internal.PopulationEntry, <-chan internal.Rejection, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// create reader
 	reader := csv.NewReader(file)
 	reader.Comma = '|'
 
+	// resolve the columns from the header row
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+
+	if p.columns, err = resolvePopulationColumns(header); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	p.read.Store(reader.InputOffset())
+
 	// make the reading thread
 	outgoing := make(chan internal.PopulationEntry)
-	incomming := make(chan []string, 100)
+	rejects := make(chan internal.Rejection, 100)
+	incomming := make(chan record, 100)
 
 	// processesing thread
 	for i := 0; i < 8; i++ {
 		p.wg.Add(1)
-		go p.EntryWorker(incomming, outgoing)
+		go p.EntryWorker(ctx, path, incomming, outgoing, rejects)
 	}
 
 	go func() {
@@ -46,18 +101,26 @@ func (p *PopulationParser) ParseFile(path string) (<-chan internal.PopulationEnt
 
 		for {
 			// read record from csv
-			record, err := reader.Read()
+			fields, err := reader.Read()
+			p.read.Store(reader.InputOffset())
 			if err != nil {
 				if errors.Is(err, io.EOF) {
 					break
 				}
 
-				log.Printf("error whilst reading record: %v", err)
+				reject := internal.NewRejection(path, errorLine(err), fields, internal.NewRejectError(internal.RejectMalformedRecord, err))
+				if !send(ctx, rejects, reject) {
+					break
+				}
+
 				continue
 			}
 
 			// process the record
-			incomming <- record
+			line, _ := reader.FieldPos(0)
+			if !send(ctx, incomming, record{line: line, fields: fields}) {
+				break
+			}
 		}
 
 		// close the incomming channel
@@ -66,64 +129,145 @@ func (p *PopulationParser) ParseFile(path string) (<-chan internal.PopulationEnt
 		// wait for all data to be processed
 		p.wg.Wait()
 
-		// close the outgoing channel (done)
+		// close the outgoing channels (done)
 		close(outgoing)
+		close(rejects)
 	}()
 
-	return outgoing, nil
+	return outgoing, rejects, nil
+}
+
+// BytesRead returns how much of the file being parsed was read.
+func (p *PopulationParser) BytesRead() int64 {
+	return p.read.Load()
 }
 
-func (p *PopulationParser) EntryWorker(incomming <-chan []string, outgoing chan<- internal.PopulationEntry) {
+func (p *PopulationParser) EntryWorker(ctx context.Context, path string, incomming <-chan record, outgoing chan<- internal.PopulationEntry, rejects chan<- internal.Rejection) {
 	for record := range incomming {
 		// parse record
-		entry, err := p.ParseEntry(record)
+		entry, err := p.ParseEntry(record.fields)
 		if err != nil {
-			log.Printf("error whilst parsing entry: %v", err)
+			send(ctx, rejects, internal.NewRejection(path, record.line, record.fields, err))
+			continue
+		}
+
+		// drop the city-wide aggregate rows
+		if p.skipCity && entry.District == internal.LocationCity {
 			continue
 		}
 
 		// send to channel
-		outgoing <- *entry
+		send(ctx, outgoing, *entry)
 	}
 
 	// notify of thread finishing
 	p.wg.Done()
 }
 
+// resolvePopulationColumns finds every required column in the header row.
+func resolvePopulationColumns(header []string) (populationColumns, error) {
+	var columns populationColumns
+	var err error
+	names := headerColumns(header)
+
+	if columns.year, err = findColumn(names, "year", populationHeaders.Year); err != nil {
+		return columns, err
+	}
+
+	if columns.district, err = findColumn(names, "district", populationHeaders.District); err != nil {
+		return columns, err
+	}
+
+	if columns.sex, err = findColumn(names, "gender", populationHeaders.Sex); err != nil {
+		return columns, err
+	}
+
+	if columns.age, err = findColumn(names, "age", populationHeaders.Age); err != nil {
+		return columns, err
+	}
+
+	if columns.population, err = findColumn(names, "population", populationHeaders.Population); err != nil {
+		return columns, err
+	}
+
+	if columns.lifeCourse, err = findColumn(names, "life course", populationHeaders.LifeCourse); err != nil {
+		columns.lifeCourse = -1
+	}
+
+	if columns.ageGroup, err = findColumn(names, "age group", populationHeaders.AgeGroup); err != nil {
+		columns.ageGroup = -1
+	}
+
+	return columns, nil
+}
+
 func (p *PopulationParser) ParseEntry(entry []string) (*internal.PopulationEntry, error) {
 	// parse year
-	year, err := strconv.Atoi(entry[0])
+	year, err := strconv.Atoi(entry[p.columns.year])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse year %q", entry[0])
+		return nil, internal.NewRejectError(internal.RejectBadNumber, fmt.Errorf("failed to parse year %q", entry[p.columns.year]))
 	}
 
 	// parse location
-	district, err := strconv.Atoi(entry[1])
+	district, err := strconv.Atoi(entry[p.columns.district])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse district %q", entry[0])
+		return nil, internal.NewRejectError(internal.RejectUnknownDistrict, fmt.Errorf("failed to parse district %q", entry[p.columns.district]))
 	}
 
-	// fix district
-	if district == 0 {
-		district = 99
+	// sex
+	sex, ok := p.sexes[internal.NormalizeName(entry[p.columns.sex])]
+	if !ok {
+		return nil, internal.NewRejectError(internal.RejectUnknownGender, fmt.Errorf("unknown gender %q", entry[p.columns.sex]))
 	}
 
 	// age
-	age, err := strconv.Atoi(entry[4])
+	age, err := strconv.Atoi(entry[p.columns.age])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse age %q", entry[4])
+		return nil, internal.NewRejectError(internal.RejectBadNumber, fmt.Errorf("failed to parse age %q", entry[p.columns.age]))
+	}
+
+	// classifications
+	lifeCourse, err := classify(entry, p.columns.lifeCourse, internal.LifeCourses, "life course", age)
+	if err != nil {
+		return nil, err
+	}
+
+	ageGroup, err := classify(entry, p.columns.ageGroup, internal.AgeGroups, "age group", age)
+	if err != nil {
+		return nil, err
 	}
 
 	// population
-	population, err := strconv.Atoi(entry[7])
+	population, err := strconv.Atoi(entry[p.columns.population])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse population %q", entry[7])
+		return nil, internal.NewRejectError(internal.RejectBadNumber, fmt.Errorf("failed to parse population %q", entry[p.columns.population]))
 	}
 
 	return &internal.PopulationEntry{
 		Year:       year,
 		Age:        age,
+		Sex:        sex,
+		LifeCourse: lifeCourse,
+		AgeGroup:   ageGroup,
 		Population: population,
 		District:   district,
 	}, nil
 }
+
+// classify resolves the band named in column, checking that it contains age.
+func classify(entry []string, column int, bands []internal.AgeBand, kind string, age int) (*int, error) {
+	if column < 0 {
+		return nil, nil
+	}
+
+	band, ok := internal.FindBand(bands, entry[column])
+	if !ok {
+		return nil, internal.NewRejectError(internal.RejectBadAgeBand, fmt.Errorf("unknown %s %q", kind, entry[column]))
+	}
+
+	if !band.Contains(age) {
+		return nil, internal.NewRejectError(internal.RejectBadAgeBand, fmt.Errorf("age %d is not in %s %q", age, kind, band.Name))
+	}
+
+	return &band.ID, nil
+}
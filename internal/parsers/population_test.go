@@ -0,0 +1,101 @@
+package parsers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+func TestResolvePopulationColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   populationColumns
+		err    bool
+	}{
+		{
+			name:   "extract",
+			header: "ANO|CODIGO_LOCALIDAD|NOMBRE_LOCALIDAD|SEXO|EDAD|CURSODEVIDA|GRUPOEDAD|POBLACION_7",
+			want:   populationColumns{year: 0, district: 1, sex: 3, age: 4, lifeCourse: 5, ageGroup: 6, population: 7},
+		},
+		{
+			name:   "spelled otherwise without classifications",
+			header: "\ufeffPoblación|Edad|Género|Cod_Localidad|Año",
+			want:   populationColumns{year: 4, district: 3, sex: 2, age: 1, lifeCourse: -1, ageGroup: -1, population: 0},
+		},
+		{
+			name:   "missing gender",
+			header: "ANO|CODIGO_LOCALIDAD|EDAD|POBLACION",
+			err:    true,
+		},
+		{
+			name:   "missing population",
+			header: "ANO|CODIGO_LOCALIDAD|SEXO|EDAD|CURSODEVIDA|GRUPOEDAD",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := resolvePopulationColumns(strings.Split(tt.header, "|"))
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: resolved %+v, want an error", tt.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%s: resolved %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFileMissingHeader(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	mapping, err := BuiltinProgramMapping(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the date has no index to fall back on
+	mapping.Columns.Date = nil
+	mapping.Headers.Date = []string{"FECHA_ATENCION"}
+
+	tests := []struct {
+		name   string
+		parse  func() error
+		column string
+	}{
+		{"population", func() error {
+			_, _, err := NewPopulationParser(false, &internal.ReferenceData{}).ParseFile(context.Background(), write("population.txt", "ANO|CODIGO_LOCALIDAD|EDAD|POBLACION\n2023|1|30|100\n"))
+			return err
+		}, "gender"},
+		{"program", func() error {
+			_, _, err := NewProgramParser(mapping, &internal.ReferenceData{}).ParseFile(context.Background(), write("program.csv", "ID,LOCALIDAD,EPS,NACIMIENTO,SEXO,FECHA\n1,Suba,Sanitas,1/2/1990,M,3/4/2024\n"))
+			return err
+		}, "date"},
+	}
+
+	for _, tt := range tests {
+		err := tt.parse()
+		if err == nil || !strings.Contains(err.Error(), "missing "+tt.column+" column") {
+			t.Errorf("%s: ParseFile = %v, want a missing %s column error", tt.name, err, tt.column)
+		}
+	}
+}
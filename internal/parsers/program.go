@@ -0,0 +1,201 @@
+package parsers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+type ProgramParser struct {
+	mapping *ProgramMapping
+	columns ColumnMapping
+	read    atomic.Int64
+	wg      sync.WaitGroup
+}
+
+// NewProgramParser creates a parser for the extracts described by mapping, resolving values through reference.
+func NewProgramParser(mapping *ProgramMapping, reference *internal.ReferenceData) internal.EntryParser[internal.ProgramEntry] {
+	mapping.Bind(reference)
+
+	return &ProgramParser{
+		mapping: mapping,
+		columns: mapping.Columns,
+	}
+}
+
+func (p *ProgramParser) ParseFile(ctx context.Context, path string) (<-chan internal.ProgramEntry, <-chan internal.Rejection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create reader
+	reader := csv.NewReader(file)
+	reader.Comma = p.mapping.Comma()
+
+	// resolve the columns from the header row
+	if p.mapping.Header {
+		header, err := reader.Read()
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+		}
+
+		if p.columns, err = p.mapping.ResolveColumns(header); err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	p.read.Store(reader.InputOffset())
+
+	// make the reading thread
+	outgoing := make(chan internal.ProgramEntry)
+	rejects := make(chan internal.Rejection, 100)
+	incomming := make(chan record, 100)
+
+	// processesing thread
+	for i := 0; i < 8; i++ {
+		p.wg.Add(1)
+		go p.EntryWorker(ctx, path, incomming, outgoing, rejects)
+	}
+
+	// reading thread
+	go func() {
+		defer file.Close()
+
+		for {
+			// read record from csv
+			fields, err := reader.Read()
+			p.read.Store(reader.InputOffset())
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				reject := internal.NewRejection(path, errorLine(err), fields, internal.NewRejectError(internal.RejectMalformedRecord, err))
+				if !send(ctx, rejects, reject) {
+					break
+				}
+
+				continue
+			}
+
+			// process the record
+			line, _ := reader.FieldPos(0)
+			if !send(ctx, incomming, record{line: line, fields: fields}) {
+				break
+			}
+		}
+
+		// close the incomming channel
+		close(incomming)
+
+		// wait for all data to be processed
+		p.wg.Wait()
+
+		// close the outgoing channels (done)
+		close(outgoing)
+		close(rejects)
+	}()
+
+	return outgoing, rejects, nil
+}
+
+// BytesRead returns how much of the file being parsed was read.
+func (p *ProgramParser) BytesRead() int64 {
+	return p.read.Load()
+}
+
+func (p *ProgramParser) EntryWorker(ctx context.Context, path string, incomming <-chan record, outgoing chan<- internal.ProgramEntry, rejects chan<- internal.Rejection) {
+	for record := range incomming {
+		// parse record
+		entry, err := p.ParseEntry(record.fields)
+		if err != nil {
+			send(ctx, rejects, internal.NewRejection(path, record.line, record.fields, err))
+			continue
+		}
+
+		// send to channel
+		send(ctx, outgoing, *entry)
+	}
+
+	// notify of thread finishing
+	p.wg.Done()
+}
+
+func (p *ProgramParser) ParseEntry(entry []string) (*internal.ProgramEntry, error) {
+	columns := p.columns
+
+	// parse location
+	location, err := p.mapping.Districts.Resolve("district", column(entry, columns.District))
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectUnknownDistrict, err)
+	}
+
+	// parse insurer
+	insurer, err := p.mapping.Insurers.Resolve("insurer", column(entry, columns.Insurer))
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectUnknownInsurer, err)
+	}
+
+	// parse sex
+	sex, err := p.mapping.Sexes.Resolve("gender", column(entry, columns.Sex))
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectUnknownGender, err)
+	}
+
+	// parse date
+	date, err := parseDate(column(entry, columns.Date), p.mapping.Layouts.Date)
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectBadDate, fmt.Errorf("error parsing date: %q", column(entry, columns.Date)))
+	}
+
+	// parse age
+	birthday, err := parseDate(column(entry, columns.Birthdate), p.mapping.Layouts.Birthdate)
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectBadBirthdate, fmt.Errorf("error parsing birthday: %q", column(entry, columns.Birthdate)))
+	}
+
+	age, err := internal.AgeAt(birthday, date)
+	if err != nil {
+		return nil, internal.NewRejectError(internal.RejectBadAge, err)
+	}
+
+	return &internal.ProgramEntry{
+		Program:   p.mapping.Program,
+		Location:  location,
+		EPS:       insurer,
+		Sex:       sex,
+		Age:       age,
+		Birthdate: birthday,
+		Date:      date,
+	}, nil
+}
+
+// PrintUnresolved writes the district and insurer values that could not be resolved.
+func (p *ProgramParser) PrintUnresolved(w io.Writer) {
+	if p.mapping.Districts.districts != nil {
+		p.mapping.Districts.districts.PrintUnresolved(w)
+	}
+
+	if p.mapping.Insurers.insurers != nil {
+		p.mapping.Insurers.insurers.PrintUnresolved(w)
+	}
+}
+
+// column returns the value at index, or an empty value when the column is not mapped.
+func column(entry []string, index *int) string {
+	if index == nil || *index < 0 || *index >= len(entry) {
+		return ""
+	}
+
+	return entry[*index]
+}
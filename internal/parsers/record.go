@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/foxinuni/prueba-patrones/internal"
+)
+
+// record is a raw csv record along with the line it was read from.
+type record struct {
+	line   int
+	fields []string
+}
+
+// errorLine returns the line a csv reading error started at.
+func errorLine(err error) int {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.StartLine
+	}
+
+	return 0
+}
+
+// send delivers value unless ctx is cancelled first, reporting whether it was delivered.
+func send[T any](ctx context.Context, channel chan<- T, value T) bool {
+	select {
+	case channel <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// headerColumns indexes a header row by normalized column name.
+func headerColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = normalizeHeader(name)
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+
+	return columns
+}
+
+// normalizeHeader normalizes a column name like the reference names, so case, accents, spaces
+// and underscores do not matter, dropping the byte order mark some exports start with.
+func normalizeHeader(name string) string {
+	return internal.NormalizeName(strings.TrimPrefix(name, "\ufeff"))
+}
+
+// findColumn returns the index of the first alias present in the header.
+func findColumn(columns map[string]int, kind string, aliases []string) (int, error) {
+	for _, alias := range aliases {
+		if i, ok := columns[normalizeHeader(alias)]; ok {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("missing %s column, expected one of: %s", kind, strings.Join(aliases, ", "))
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ByteCounter is implemented by the parsers reporting how much of the file they have read.
+type ByteCounter interface {
+	BytesRead() int64
+}
+
+// FlushCounter is implemented by the buffering stores, reporting how many entries they have written.
+type FlushCounter interface {
+	Flushed() int64
+}
+
+// Progress is a snapshot of the counters of an import. Parsed counts the records read from the
+// file, either accepted or rejected, Stored the entries handed to the store and Flushed the ones
+// written by it.
+type Progress struct {
+	FileSize  int64
+	BytesRead int64
+	Parsed    int64
+	Rejected  int64
+	Stored    int64
+	Flushed   int64
+	Elapsed   time.Duration
+}
+
+// RowsPerSecond returns the records parsed per second.
+func (p Progress) RowsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Parsed) / p.Elapsed.Seconds()
+}
+
+// Percent returns how much of the file was read, the size of a file that cannot be stat'd is unknown.
+func (p Progress) Percent() (float64, bool) {
+	if p.FileSize <= 0 {
+		return 0, false
+	}
+
+	return 100 * float64(p.BytesRead) / float64(p.FileSize), true
+}
+
+// ETA estimates the time left to read the rest of the file at the current pace.
+func (p Progress) ETA() (time.Duration, bool) {
+	if p.FileSize <= 0 || p.BytesRead <= 0 {
+		return 0, false
+	}
+
+	left := float64(p.FileSize-p.BytesRead) / float64(p.BytesRead)
+	return time.Duration(left * float64(p.Elapsed)).Round(time.Second), true
+}
+
+// String returns a one line summary, used for the periodic progress line.
+func (p Progress) String() string {
+	read := formatBytes(p.BytesRead)
+	if percent, ok := p.Percent(); ok {
+		read = fmt.Sprintf("%.1f%% (%s of %s)", percent, formatBytes(p.BytesRead), formatBytes(p.FileSize))
+	}
+
+	eta := "unknown"
+	if left, ok := p.ETA(); ok {
+		eta = left.String()
+	}
+
+	return fmt.Sprintf("read %s, %d parsed, %d rejected, %d flushed, %.0f rows/s, ETA %s",
+		read, p.Parsed, p.Rejected, p.Flushed, p.RowsPerSecond(), eta)
+}
+
+// Print writes the final counters as a table.
+func (p Progress) Print(w io.Writer) {
+	fmt.Fprintln(w, "Import summary:")
+	fmt.Fprintf(w, "  %-12s %s of %s\n", "read", formatBytes(p.BytesRead), formatBytes(p.FileSize))
+	fmt.Fprintf(w, "  %-12s %d\n", "parsed", p.Parsed)
+	fmt.Fprintf(w, "  %-12s %d\n", "rejected", p.Rejected)
+	fmt.Fprintf(w, "  %-12s %d\n", "stored", p.Stored)
+	fmt.Fprintf(w, "  %-12s %d\n", "flushed", p.Flushed)
+	fmt.Fprintf(w, "  %-12s %s\n", "elapsed", p.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "  %-12s %.0f\n", "rows/s", p.RowsPerSecond())
+}
+
+// formatBytes formats a size with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
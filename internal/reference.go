@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"maps"
+)
+
+const (
+	ReferenceDistrict = "district"
+	ReferenceInsurer  = "insurer"
+	ReferenceGender   = "gender"
+)
+
+// ReferenceTables maps every kind of reference data onto the table holding it.
+var ReferenceTables = map[string]string{
+	ReferenceDistrict: "districts",
+	ReferenceInsurer:  "insurers",
+	ReferenceGender:   "gender",
+}
+
+// ReferenceData holds the names of the districts, insurers and genders along with their aliases,
+// every name and alias is normalized.
+type ReferenceData struct {
+	Names   map[string]map[string]int
+	Aliases map[string]map[string]int
+}
+
+// Lookup returns every name and alias of a kind of reference data along with its id.
+func (d *ReferenceData) Lookup(kind string) map[string]int {
+	lookup := map[string]int{}
+	maps.Copy(lookup, d.Names[kind])
+	maps.Copy(lookup, d.Aliases[kind])
+
+	return lookup
+}
+
+type PgReferenceStore struct {
+	db Querier
+}
+
+func NewPgReferenceStore(db Querier) *PgReferenceStore {
+	return &PgReferenceStore{
+		db: db,
+	}
+}
+
+// Load reads the reference tables and the aliases table.
+func (s *PgReferenceStore) Load(ctx context.Context) (*ReferenceData, error) {
+	data := &ReferenceData{
+		Names:   map[string]map[string]int{},
+		Aliases: map[string]map[string]int{},
+	}
+
+	for kind, table := range ReferenceTables {
+		names, err := s.query(ctx, "SELECT name, id FROM "+table)
+		if err != nil {
+			return nil, err
+		}
+
+		data.Names[kind] = names
+		if data.Aliases[kind], err = s.query(ctx, "SELECT alias, target_id FROM aliases WHERE kind = $1", kind); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// query reads (name, id) rows into a map by normalized name.
+func (s *PgReferenceStore) query(ctx context.Context, sql string, args ...any) (map[string]int, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := map[string]int{}
+	for rows.Next() {
+		var name string
+		var id int
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+
+		values[NormalizeName(name)] = id
+	}
+
+	return values, rows.Err()
+}
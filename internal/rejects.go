@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	RejectMalformedRecord = "malformed_record"
+	RejectUnknownDistrict = "unknown_district"
+	RejectUnknownInsurer  = "unknown_insurer"
+	RejectUnknownGender   = "unknown_gender"
+	RejectBadDate         = "bad_date"
+	RejectBadBirthdate    = "bad_birthdate"
+	RejectBadAge          = "bad_age"
+	RejectBadAgeBand      = "bad_age_band"
+	RejectBadNumber       = "bad_number"
+	RejectUnknown         = "unknown"
+)
+
+// RejectError is returned by parsers when a record cannot be turned into an entry.
+type RejectError struct {
+	Reason string
+	Err    error
+}
+
+func NewRejectError(reason string, err error) error {
+	return &RejectError{Reason: reason, Err: err}
+}
+
+func (e *RejectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+// RejectReason returns the reason code of a parsing error.
+func RejectReason(err error) string {
+	var reject *RejectError
+	if errors.As(err, &reject) {
+		return reject.Reason
+	}
+
+	return RejectUnknown
+}
+
+// Rejection is a record that was dropped by a parser.
+type Rejection struct {
+	File   string
+	Line   int
+	Fields []string
+	Reason string
+	Error  string
+}
+
+func NewRejection(file string, line int, fields []string, err error) Rejection {
+	return Rejection{
+		File:   file,
+		Line:   line,
+		Fields: fields,
+		Reason: RejectReason(err),
+		Error:  err.Error(),
+	}
+}
+
+type RejectWriter interface {
+	WriteReject(reject *Rejection) error
+}
+
+// RejectSummary counts the rejected records per reason.
+type RejectSummary map[string]int
+
+func (s RejectSummary) Total() int {
+	total := 0
+	for _, count := range s {
+		total += count
+	}
+
+	return total
+}
+
+// Print writes the summary sorted by reason.
+func (s RejectSummary) Print(w io.Writer) {
+	reasons := make([]string, 0, len(s))
+	for reason := range s {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	fmt.Fprintf(w, "Rejected records: %d\n", s.Total())
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "  %-20s %d\n", reason, s[reason])
+	}
+}
+
+type CsvRejectWriter struct {
+	file   *os.File
+	writer *csv.Writer
+	mu     sync.Mutex // Mutex to protect concurrent access to the writer
+}
+
+// NewCsvRejectWriter creates the rejects file and writes its header.
+func NewCsvRejectWriter(path string) (*CsvRejectWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"source_file", "line", "reason", "error", "record"}); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &CsvRejectWriter{
+		file:   file,
+		writer: writer,
+	}, nil
+}
+
+func (w *CsvRejectWriter) WriteReject(reject *Rejection) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.writer.Write([]string{
+		reject.File,
+		strconv.Itoa(reject.Line),
+		reject.Reason,
+		reject.Error,
+		strings.Join(reject.Fields, "|"),
+	})
+}
+
+func (w *CsvRejectWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+type PgRejectStore struct {
+	pool  *pgxpool.Pool
+	runID int
+}
+
+func NewPgRejectStore(pool *pgxpool.Pool, runID int) RejectWriter {
+	return &PgRejectStore{
+		pool:  pool,
+		runID: runID,
+	}
+}
+
+func (s *PgRejectStore) WriteReject(reject *Rejection) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO rejects (source_file, line, reason, error, record, run_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, reject.File, reject.Line, reject.Reason, reject.Error, reject.Fields, s.runID)
+
+	return err
+}
+
+// MultiRejectWriter writes every rejection to all of the given writers.
+type MultiRejectWriter []RejectWriter
+
+func (m MultiRejectWriter) WriteReject(reject *Rejection) error {
+	for _, writer := range m {
+		if err := writer.WriteReject(reject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy retries the writes failing with a transient error, waiting an exponential
+// backoff with jitter between attempts. It must not be used inside a transaction, a failed
+// statement aborts it and every retry would fail as well.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// NewRetryPolicy returns a policy retrying up to maxRetries times, starting at 200ms and
+// backing off up to 10s. Zero retries disables it.
+func NewRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  200 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// Do runs op until it succeeds, fails with an error that is not retryable or runs out of
+// retries, logging each retry of what. It returns the last error.
+func (p RetryPolicy) Do(ctx context.Context, what string, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt > p.MaxRetries || ctx.Err() != nil || !IsRetryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		log.Printf("retrying %s in %v (retry %d of %d): %v", what, delay.Round(time.Millisecond), attempt, p.MaxRetries, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff doubles the delay with each attempt up to MaxDelay, picking a random delay in its
+// upper half so the workers retrying together do not hit the database at once.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if shift := attempt - 1; shift < 32 && p.BaseDelay<<shift < p.MaxDelay {
+		delay = p.BaseDelay << shift
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	return delay/2 + rand.N(delay/2)
+}
+
+// IsRetryable tells whether err is a transient database failure that is safe to send again:
+// a serialization failure, a deadlock or too many connections, which roll the batch back, or
+// a failure before anything was sent. A connection dropping once the batch was sent is not,
+// the batch may have been committed before the reply was lost.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "53300":
+			return true
+		}
+	}
+
+	return pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// unsentError is a connection failure before anything was written, as pgconn reports it.
+type unsentError struct{}
+
+func (unsentError) Error() string     { return "dial failed" }
+func (unsentError) SafeToRetry() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, true},
+		{"wrapped deadlock", fmt.Errorf("batch: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"not sent", unsentError{}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"admin shutdown once sent", &pgconn.PgError{Code: "08006"}, false},
+		{"connection reset once sent", fmt.Errorf("read: %w", syscall.ECONNRESET), false},
+		{"unexpected EOF", io.ErrUnexpectedEOF, false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.want {
+			t.Errorf("IsRetryable(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{40, time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			delay := policy.backoff(test.attempt)
+			if delay < test.ceiling/2 || delay >= test.ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v)", test.attempt, delay, test.ceiling/2, test.ceiling)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	deadlock := &pgconn.PgError{Code: "40P01"}
+
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		calls    int
+		fails    bool
+	}{
+		{"succeeds after retries", 2, deadlock, 3, false},
+		{"gives up after the limit", 10, deadlock, 4, true},
+		{"does not retry permanent errors", 10, &pgconn.PgError{Code: "23505"}, 1, true},
+	}
+
+	for _, test := range tests {
+		calls := 0
+		err := policy.Do(context.Background(), test.name, func() error {
+			calls++
+			if calls <= test.failures {
+				return test.err
+			}
+
+			return nil
+		})
+
+		if calls != test.calls || (err != nil) != test.fails {
+			t.Errorf("%s: %d calls returning %v, want %d calls", test.name, calls, err, test.calls)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	RunKindProgram    = "program"
+	RunKindPopulation = "population"
+)
+
+const (
+	RunRunning     = "running"
+	RunStaged      = "staged"
+	RunCompleted   = "completed"
+	RunFailed      = "failed"
+	RunInterrupted = "interrupted"
+	RunReplaced    = "replaced"
+)
+
+// ImportRun is a row of the import_runs ledger.
+type ImportRun struct {
+	ID         int
+	Kind       string
+	Program    int
+	File       string
+	Hash       string
+	Status     string
+	Stored     int
+	Rejected   int
+	Failed     int
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// RunStatus returns the status of a run that ended with err. A run whose store failures stayed
+// within the error budget is completed, its lost rows are recorded along with it.
+func RunStatus(err error) string {
+	var importErr *ImportError
+
+	switch {
+	case err == nil:
+		return RunCompleted
+	case errors.As(err, &importErr) && !importErr.Aborted:
+		return RunCompleted
+	case errors.Is(err, context.Canceled):
+		return RunInterrupted
+	default:
+		return RunFailed
+	}
+}
+
+// ExitCode returns the process exit code for an import that ended with err.
+func ExitCode(err error) int {
+	switch RunStatus(err) {
+	case RunCompleted:
+		return 0
+	case RunInterrupted:
+		return 130
+	default:
+		return 1
+	}
+}
+
+// HashFile returns the hex encoded SHA-256 of the file contents.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type PgRunStore struct {
+	db Querier
+}
+
+func NewPgRunStore(db Querier) *PgRunStore {
+	return &PgRunStore{
+		db: db,
+	}
+}
+
+// FindRun returns the latest run that imported the same file, or nil if the file was never imported.
+func (s *PgRunStore) FindRun(ctx context.Context, kind string, program int, hash string) (*ImportRun, error) {
+	run := &ImportRun{Kind: kind, Program: program, Hash: hash}
+
+	err := s.db.QueryRow(ctx, `
+		SELECT id, source_file, status, stored_rows, rejected_rows, failed_rows, started_at
+		FROM import_runs
+		WHERE kind = $1 AND program = $2 AND file_hash = $3 AND status <> $4
+		ORDER BY id DESC
+		LIMIT 1
+	`, kind, program, hash, RunReplaced).Scan(&run.ID, &run.File, &run.Status, &run.Stored, &run.Rejected, &run.Failed, &run.StartedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// GetRun returns the run with the given id.
+func (s *PgRunStore) GetRun(ctx context.Context, id int) (*ImportRun, error) {
+	run := &ImportRun{ID: id}
+
+	err := s.db.QueryRow(ctx, `
+		SELECT kind, program, source_file, file_hash, status, stored_rows, rejected_rows, failed_rows, started_at
+		FROM import_runs
+		WHERE id = $1
+	`, id).Scan(&run.Kind, &run.Program, &run.File, &run.Hash, &run.Status, &run.Stored, &run.Rejected, &run.Failed, &run.StartedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("run %d does not exist", id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// StartRun records a new running import and sets its id.
+func (s *PgRunStore) StartRun(ctx context.Context, run *ImportRun) error {
+	run.Status = RunRunning
+	run.StartedAt = time.Now()
+
+	return s.db.QueryRow(ctx, `
+		INSERT INTO import_runs (kind, program, source_file, file_hash, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, run.Kind, run.Program, run.File, run.Hash, run.Status, run.StartedAt).Scan(&run.ID)
+}
+
+// FinishRun records the final status and row counts of a run.
+func (s *PgRunStore) FinishRun(ctx context.Context, run *ImportRun, status string) error {
+	run.Status = status
+	run.FinishedAt = time.Now()
+
+	_, err := s.db.Exec(ctx, `
+		UPDATE import_runs
+		SET status = $2, stored_rows = $3, rejected_rows = $4, failed_rows = $5, finished_at = $6
+		WHERE id = $1
+	`, run.ID, run.Status, run.Stored, run.Rejected, run.Failed, run.FinishedAt)
+
+	return err
+}
+
+// ReplaceRun deletes every row imported or staged by the run and marks it as replaced.
+func (s *PgRunStore) ReplaceRun(ctx context.Context, run *ImportRun) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM entries WHERE run_id = $1`,
+			`DELETE FROM population WHERE run_id = $1`,
+			`DELETE FROM rejects WHERE run_id = $1`,
+		} {
+			if _, err := tx.Exec(ctx, query, run.ID); err != nil {
+				return err
+			}
+		}
+
+		for _, target := range []string{"entries", "population"} {
+			if _, err := tx.Exec(ctx, "DROP TABLE IF EXISTS "+pgx.Identifier{StagingTable(target, run.ID)}.Sanitize()); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(ctx, `UPDATE import_runs SET status = $2 WHERE id = $1`, run.ID, RunReplaced)
+		return err
+	})
+}
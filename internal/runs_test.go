@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRunStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status string
+		code   int
+	}{
+		{"no error", nil, RunCompleted, 0},
+		{"failures within the budget", &ImportError{First: errors.New("batch failed"), Failures: 2, FailedRows: 1000}, RunCompleted, 0},
+		{"budget exhausted", &ImportError{First: errors.New("batch failed"), Failures: 3, FailedRows: 1500, Aborted: true}, RunFailed, 1},
+		{"interrupted", fmt.Errorf("import: %w", context.Canceled), RunInterrupted, 130},
+		{"other error", errors.New("connection refused"), RunFailed, 1},
+	}
+
+	for _, tt := range tests {
+		if status := RunStatus(tt.err); status != tt.status {
+			t.Errorf("%s: RunStatus = %s, want %s", tt.name, status, tt.status)
+		}
+
+		if code := ExitCode(tt.err); code != tt.code {
+			t.Errorf("%s: ExitCode = %d, want %d", tt.name, code, tt.code)
+		}
+	}
+}
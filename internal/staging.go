@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrInvalidStaging = errors.New("staging table has invalid rows")
+
+// StagingSpec describes the unlogged table a run is loaded into before being merged into its target.
+type StagingSpec struct {
+	Target  string
+	Columns []string
+	Types   []string
+	Checks  []ValidationCheck
+}
+
+// ValidationCheck is a condition matching the invalid rows of a staging table, aliased as s.
+type ValidationCheck struct {
+	Name      string
+	Condition string
+	Args      []any
+}
+
+// ValidationResult is the number of staged rows failing a check.
+type ValidationResult struct {
+	Check   string
+	Invalid int
+}
+
+// EntriesStaging stages program entries, validating entry dates between from and to.
+func EntriesStaging(from time.Time, to time.Time) StagingSpec {
+	return StagingSpec{
+		Target:  "entries",
+		Columns: []string{"age", "program", "insurer_id", "district_id", "gender_id", "birthdate", "creation_date", "run_id"},
+		Types:   []string{"INTEGER", "INTEGER", "INTEGER", "INTEGER", "INTEGER", "DATE", "DATE", "INTEGER"},
+		Checks: []ValidationCheck{
+			{Name: "unknown_district", Condition: "NOT EXISTS (SELECT 1 FROM districts d WHERE d.id = s.district_id)"},
+			{Name: "unknown_insurer", Condition: "NOT EXISTS (SELECT 1 FROM insurers i WHERE i.id = s.insurer_id)"},
+			{Name: "unknown_gender", Condition: "NOT EXISTS (SELECT 1 FROM gender g WHERE g.id = s.gender_id)"},
+			{Name: "age_range", Condition: "s.age IS NULL OR s.age NOT BETWEEN 0 AND 120"},
+			{Name: "birthdate_after_date", Condition: "s.birthdate > s.creation_date"},
+			{Name: "date_window", Condition: "s.creation_date IS NULL OR s.creation_date NOT BETWEEN $1 AND $2", Args: []any{from, to}},
+		},
+	}
+}
+
+// PopulationStaging stages population rows.
+func PopulationStaging() StagingSpec {
+	return StagingSpec{
+		Target:  "population",
+		Columns: []string{"year", "age", "gender_id", "life_course_id", "age_group_id", "population", "district_id", "run_id"},
+		Types:   []string{"INTEGER", "INTEGER", "INTEGER", "INTEGER", "INTEGER", "INTEGER", "INTEGER", "INTEGER"},
+		Checks: []ValidationCheck{
+			{Name: "unknown_district", Condition: "NOT EXISTS (SELECT 1 FROM districts d WHERE d.id = s.district_id)"},
+			{Name: "unknown_gender", Condition: "NOT EXISTS (SELECT 1 FROM gender g WHERE g.id = s.gender_id)"},
+			{Name: "unknown_life_course", Condition: "s.life_course_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM life_courses l WHERE l.id = s.life_course_id)"},
+			{Name: "unknown_age_group", Condition: "s.age_group_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM age_groups a WHERE a.id = s.age_group_id)"},
+			{Name: "age_range", Condition: "s.age IS NULL OR s.age NOT BETWEEN 0 AND 120"},
+			{Name: "negative_population", Condition: "s.population IS NULL OR s.population < 0"},
+			{Name: "year_already_loaded", Condition: "EXISTS (SELECT 1 FROM population p WHERE p.year = s.year AND p.district_id = s.district_id AND p.age = s.age AND p.gender_id IS NOT DISTINCT FROM s.gender_id)"},
+		},
+	}
+}
+
+// StagingTable returns the name of the staging table of a run.
+func StagingTable(target string, runID int) string {
+	return fmt.Sprintf("staging_%s_%d", target, runID)
+}
+
+type PgStager struct {
+	db    Querier
+	spec  StagingSpec
+	runID int
+}
+
+func NewPgStager(db Querier, spec StagingSpec, runID int) *PgStager {
+	return &PgStager{
+		db:    db,
+		spec:  spec,
+		runID: runID,
+	}
+}
+
+// Table returns the name of the staging table, stores load into it instead of the target.
+func (s *PgStager) Table() string {
+	return StagingTable(s.spec.Target, s.runID)
+}
+
+// Create creates the unlogged staging table, without constraints so invalid rows can be inspected.
+func (s *PgStager) Create(ctx context.Context) error {
+	columns := make([]string, len(s.spec.Columns))
+	for i, column := range s.spec.Columns {
+		columns[i] = column + " " + s.spec.Types[i]
+	}
+
+	_, err := s.db.Exec(ctx, fmt.Sprintf(
+		"CREATE UNLOGGED TABLE %s (%s)",
+		pgx.Identifier{s.Table()}.Sanitize(), strings.Join(columns, ", "),
+	))
+
+	return err
+}
+
+// Validate counts the staged rows failing every check.
+func (s *PgStager) Validate(ctx context.Context) ([]ValidationResult, error) {
+	results := make([]ValidationResult, 0, len(s.spec.Checks))
+
+	for _, check := range s.spec.Checks {
+		result := ValidationResult{Check: check.Name}
+
+		err := s.db.QueryRow(ctx, fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s s WHERE %s",
+			pgx.Identifier{s.Table()}.Sanitize(), check.Condition,
+		), check.Args...).Scan(&result.Invalid)
+		if err != nil {
+			return nil, fmt.Errorf("validation %s failed: %w", check.Name, err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Merge moves every staged row into the target table in one statement and drops the staging table.
+func (s *PgStager) Merge(ctx context.Context) (int64, error) {
+	var merged int64
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		columns := strings.Join(s.spec.Columns, ", ")
+
+		tag, err := tx.Exec(ctx, fmt.Sprintf(
+			"INSERT INTO %s (%s) SELECT %s FROM %s",
+			pgx.Identifier{s.spec.Target}.Sanitize(), columns, columns, pgx.Identifier{s.Table()}.Sanitize(),
+		))
+		if err != nil {
+			return err
+		}
+
+		merged = tag.RowsAffected()
+		return NewPgStager(tx, s.spec, s.runID).Drop(ctx)
+	})
+
+	return merged, err
+}
+
+// Drop removes the staging table.
+func (s *PgStager) Drop(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", pgx.Identifier{s.Table()}.Sanitize()))
+	return err
+}
+
+// MergeValid validates the staged rows and merges them only if every check passes.
+func (s *PgStager) MergeValid(ctx context.Context) ([]ValidationResult, int64, error) {
+	results, err := s.Validate(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !Valid(results) {
+		return results, 0, fmt.Errorf("%s: %w", s.Table(), ErrInvalidStaging)
+	}
+
+	merged, err := s.Merge(ctx)
+	return results, merged, err
+}
+
+// Valid reports whether no staged row failed a check.
+func Valid(results []ValidationResult) bool {
+	for _, result := range results {
+		if result.Invalid > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PrintValidation writes the validation results of a staged run.
+func PrintValidation(w io.Writer, table string, results []ValidationResult) {
+	fmt.Fprintf(w, "Validation of %s:\n", table)
+	for _, result := range results {
+		fmt.Fprintf(w, "  %-20s %d invalid\n", result.Check, result.Invalid)
+	}
+}
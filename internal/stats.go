@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProgramStats is an EntryStore that only counts program entries and their distribution,
+// used to validate files without a database.
+type ProgramStats struct {
+	Accepted  int
+	Districts map[int]int
+	Insurers  map[int]int
+	Sexes     map[int]int
+	FirstDate time.Time
+	LastDate  time.Time
+	mu        sync.Mutex // Mutex to protect concurrent access to the counters
+}
+
+func NewProgramStats() *ProgramStats {
+	return &ProgramStats{
+		Districts: map[int]int{},
+		Insurers:  map[int]int{},
+		Sexes:     map[int]int{},
+	}
+}
+
+func (s *ProgramStats) CreateEntry(ctx context.Context, entry *ProgramEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Accepted++
+	s.Districts[entry.Location]++
+	s.Insurers[entry.EPS]++
+	s.Sexes[entry.Sex]++
+
+	if s.FirstDate.IsZero() || entry.Date.Before(s.FirstDate) {
+		s.FirstDate = entry.Date
+	}
+
+	if entry.Date.After(s.LastDate) {
+		s.LastDate = entry.Date
+	}
+
+	return nil
+}
+
+func (s *ProgramStats) Print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "Accepted records: %d\n", s.Accepted)
+	if s.Accepted > 0 {
+		fmt.Fprintf(w, "Date range: %s to %s\n", s.FirstDate.Format(time.DateOnly), s.LastDate.Format(time.DateOnly))
+	}
+
+	printDistribution(w, "district", s.Districts)
+	printDistribution(w, "insurer", s.Insurers)
+	printDistribution(w, "gender", s.Sexes)
+}
+
+// PopulationKey identifies the population rows that add up to a city-wide total.
+type PopulationKey struct {
+	Year int
+	Age  int
+	Sex  int
+}
+
+// PopulationStats is an EntryStore that only counts population rows and their distribution,
+// used to validate files without a database. The city-wide rows are kept apart and checked
+// against the sum of the district rows.
+type PopulationStats struct {
+	Accepted     int
+	Population   int
+	Districts    map[int]int
+	Years        map[int]int
+	Sexes        map[int]int
+	CityTotals   map[PopulationKey]int
+	DistrictSums map[PopulationKey]int
+	mu           sync.Mutex // Mutex to protect concurrent access to the counters
+}
+
+func NewPopulationStats() *PopulationStats {
+	return &PopulationStats{
+		Districts:    map[int]int{},
+		Years:        map[int]int{},
+		Sexes:        map[int]int{},
+		CityTotals:   map[PopulationKey]int{},
+		DistrictSums: map[PopulationKey]int{},
+	}
+}
+
+func (s *PopulationStats) CreateEntry(ctx context.Context, entry *PopulationEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Accepted++
+	s.Districts[entry.District] += entry.Population
+
+	// the city-wide rows would count everyone twice
+	key := PopulationKey{Year: entry.Year, Age: entry.Age, Sex: entry.Sex}
+	if entry.District == LocationCity {
+		s.CityTotals[key] += entry.Population
+		return nil
+	}
+
+	s.Population += entry.Population
+	s.Years[entry.Year] += entry.Population
+	s.Sexes[entry.Sex] += entry.Population
+	s.DistrictSums[key] += entry.Population
+
+	return nil
+}
+
+// Mismatches returns the keys whose city-wide total differs from the sum of its districts, sorted.
+func (s *PopulationStats) Mismatches() []PopulationKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mismatches()
+}
+
+func (s *PopulationStats) mismatches() []PopulationKey {
+	keys := []PopulationKey{}
+	for key, total := range s.CityTotals {
+		if s.DistrictSums[key] != total {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+
+		if a.Age != b.Age {
+			return a.Age < b.Age
+		}
+
+		return a.Sex < b.Sex
+	})
+
+	return keys
+}
+
+func (s *PopulationStats) Print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "Accepted records: %d (population %d)\n", s.Accepted, s.Population)
+	printDistribution(w, "year", s.Years)
+	printDistribution(w, "district", s.Districts)
+	printDistribution(w, "gender", s.Sexes)
+
+	if len(s.CityTotals) == 0 {
+		return
+	}
+
+	// compare the city-wide totals with their districts
+	mismatches := s.mismatches()
+	fmt.Fprintf(w, "City totals not matching their districts: %d of %d\n", len(mismatches), len(s.CityTotals))
+	for i, key := range mismatches {
+		if i == 10 {
+			fmt.Fprintf(w, "  ...\n")
+			break
+		}
+
+		fmt.Fprintf(w, "  year %d age %d gender %d: city %d, districts %d\n", key.Year, key.Age, key.Sex, s.CityTotals[key], s.DistrictSums[key])
+	}
+}
+
+// printDistribution writes the counts of a distribution sorted by id.
+func printDistribution(w io.Writer, name string, counts map[int]int) {
+	ids := make([]int, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Fprintf(w, "By %s:\n", name)
+	for _, id := range ids {
+		fmt.Fprintf(w, "  %-20d %d\n", id, counts[id])
+	}
+}
@@ -2,169 +2,395 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// EntryStore receives the parsed entries of an import. Stores that buffer entries also
+// implement io.Closer, the controller closes them at the end of an import to flush what is left.
 type EntryStore[T any] interface {
-	CreateEntry(entry *T) error
+	CreateEntry(ctx context.Context, entry *T) error
 }
 
-type PgProgramStore struct {
-	pool *pgxpool.Pool
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx, so the stores can write inside a
+// transaction. A pgx.Tx is a single connection and must only be used by one goroutine at a time.
+type Querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 }
 
-func NewPgProgramStore(pool *pgxpool.Pool) EntryStore[ProgramEntry] {
-	return &PgProgramStore{
-		pool: pool,
+// BatchError reports a buffered batch that failed to be written, every row in it is counted as
+// lost. Batches are never cancelled once sent, so a batch is either written or reported here.
+type BatchError struct {
+	Batch int
+	Rows  int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d (%d rows) failed: %v", e.Batch, e.Rows, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// FailedRows returns the number of rows lost by a store error, adding up joined errors.
+func FailedRows(err error) int {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		rows := 0
+		for _, err := range joined.Unwrap() {
+			rows += FailedRows(err)
+		}
+
+		return rows
+	}
+
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Rows
+	}
+
+	return 1
+}
+
+// backgroundFlusher calls flush periodically until stopped, so a partial buffer is written
+// even when no entries arrive.
+type backgroundFlusher struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// startFlusher runs flush every interval, a non positive interval never flushes.
+func startFlusher(interval time.Duration, flush func()) *backgroundFlusher {
+	f := &backgroundFlusher{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if interval <= 0 {
+		close(f.done)
+		return f
 	}
+
+	go func() {
+		defer close(f.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	return f
 }
 
-func (s *PgProgramStore) CreateEntry(entry *ProgramEntry) error {
-	_, err := s.pool.Exec(context.Background(), `
-		INSERT INTO entries (age, program, insurer_id, district_id, gender_id, creation_date)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, entry.Age, entry.Program, entry.EPS, entry.Location, entry.Sex, entry.Date)
+// Stop stops the flusher and waits for a running flush to finish.
+func (f *backgroundFlusher) Stop() {
+	f.once.Do(func() {
+		close(f.stop)
+	})
 
+	<-f.done
+}
+
+// RowMapper maps entries onto the columns of a table.
+type RowMapper[T any] struct {
+	Table   string
+	Columns []string
+	Row     func(entry *T) []any
+}
+
+// ProgramRows maps program entries onto the entries table, or its staging table, of a run.
+func ProgramRows(table string, runID int) RowMapper[ProgramEntry] {
+	return RowMapper[ProgramEntry]{
+		Table:   table,
+		Columns: []string{"age", "program", "insurer_id", "district_id", "gender_id", "birthdate", "creation_date", "run_id"},
+		Row: func(entry *ProgramEntry) []any {
+			return []any{entry.Age, entry.Program, entry.EPS, entry.Location, entry.Sex, entry.Birthdate, entry.Date, runID}
+		},
+	}
+}
+
+// PopulationRows maps population entries onto the population table, or its staging table, of a run.
+func PopulationRows(table string, runID int) RowMapper[PopulationEntry] {
+	return RowMapper[PopulationEntry]{
+		Table:   table,
+		Columns: []string{"year", "age", "gender_id", "life_course_id", "age_group_id", "population", "district_id", "run_id"},
+		Row: func(entry *PopulationEntry) []any {
+			return []any{entry.Year, entry.Age, entry.Sex, entry.LifeCourse, entry.AgeGroup, entry.Population, entry.District, runID}
+		},
+	}
+}
+
+// Insert returns the statement inserting a single row.
+func (m RowMapper[T]) Insert() string {
+	params := make([]string, len(m.Columns))
+	for i := range m.Columns {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		pgx.Identifier{m.Table}.Sanitize(), strings.Join(m.Columns, ", "), strings.Join(params, ", "),
+	)
+}
+
+// PgStore inserts every entry on its own.
+type PgStore[T any] struct {
+	db     Querier
+	mapper RowMapper[T]
+	insert string
+}
+
+func NewPgStore[T any](db Querier, mapper RowMapper[T]) *PgStore[T] {
+	return &PgStore[T]{
+		db:     db,
+		mapper: mapper,
+		insert: mapper.Insert(),
+	}
+}
+
+// CreateEntry inserts the entry, once started the insert is not cancelled with ctx.
+func (s *PgStore[T]) CreateEntry(ctx context.Context, entry *T) error {
+	_, err := s.db.Exec(context.WithoutCancel(ctx), s.insert, s.mapper.Row(entry)...)
 	return err
 }
 
-type PgBufferedProgramStore struct {
-	pool         *pgxpool.Pool
-	batch        *pgx.Batch
-	maxBatchSize int
+// bufferedStore buffers the rows of its entries and writes them with send, once the buffer is
+// full or once the timeout elapses. A full buffer is swapped for an empty one and written
+// outside the mutex, so the other workers keep buffering while up to maxInFlight writes run
+// on their own connections. A background flusher writes the buffer on timeout even when no
+// entries arrive, its errors are returned by the next CreateEntry or by Close. A write failing
+// with a transient error is sent again following the retry policy.
+type bufferedStore[T any] struct {
+	mapper       RowMapper[T]
+	unit         string
+	send         func(ctx context.Context, rows [][]any) error
+	rows         [][]any
+	writes       int
+	maxSize      int
+	inFlight     chan struct{}
+	retry        RetryPolicy
 	timeout      time.Duration
 	lastExecuted time.Time
+	flusher      *backgroundFlusher
+	flushed      atomic.Int64
+	pending      []error
 	mu           sync.Mutex // Mutex to protect concurrent access to shared state
 }
 
-// NewPgBufferedProgramStore initializes a new PgBufferedEntryStore with a batch size limit and timeout.
-func NewPgBufferedProgramStore(pool *pgxpool.Pool, maxBatchSize int, timeout time.Duration) EntryStore[ProgramEntry] {
-	return &PgBufferedProgramStore{
-		pool:         pool,
-		batch:        &pgx.Batch{},
-		maxBatchSize: maxBatchSize,
+// newBufferedStore initializes a bufferedStore writing up to maxSize rows with send, unit names
+// a write in the logs. It must be closed to stop its background flusher.
+func newBufferedStore[T any](mapper RowMapper[T], unit string, send func(ctx context.Context, rows [][]any) error, maxSize int, maxInFlight int, retry RetryPolicy, timeout time.Duration) *bufferedStore[T] {
+	s := &bufferedStore[T]{
+		mapper:       mapper,
+		unit:         unit,
+		send:         send,
+		rows:         make([][]any, 0, maxSize),
+		maxSize:      maxSize,
+		inFlight:     make(chan struct{}, max(maxInFlight, 1)),
+		retry:        retry,
 		timeout:      timeout,
 		lastExecuted: time.Now(),
 	}
+
+	s.flusher = startFlusher(timeout/2, s.flushExpired)
+	return s
 }
 
-// AddEntryToBatch adds an entry to the batch and checks if it's time to execute the batch.
-func (s *PgBufferedProgramStore) CreateEntry(entry *ProgramEntry) error {
-	// Lock the mutex to ensure only one goroutine can modify the batch at a time
+// flushExpired writes the buffer when the timeout elapsed, keeping the error for the controller.
+func (s *bufferedStore[T]) flushExpired() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	if time.Since(s.lastExecuted) < s.timeout {
+		s.mu.Unlock()
+		return
+	}
 
-	// Add the entry to the batch
-	s.batch.Queue(`
-		INSERT INTO entries (age, program, insurer_id, district_id, gender_id, creation_date)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, entry.Age, entry.Program, entry.EPS, entry.Location, entry.Sex, entry.Date)
+	rows, number := s.take()
+	s.mu.Unlock()
 
-	// Check if the batch size has reached the maximum or if the timeout has elapsed
-	if s.batch.Len() >= s.maxBatchSize || time.Since(s.lastExecuted) >= s.timeout {
-		if err := s.ExecuteBatch(context.Background()); err != nil {
-			return err
-		}
+	if err := s.write(context.Background(), rows, number); err != nil {
+		s.mu.Lock()
+		s.pending = append(s.pending, err)
+		s.mu.Unlock()
 	}
-
-	return nil
 }
 
-// ExecuteBatch sends all the queued inserts in one batch.
-func (s *PgBufferedProgramStore) ExecuteBatch(ctx context.Context) error {
-	// Send the batch to the database
-	br := s.pool.SendBatch(ctx, s.batch)
-	defer br.Close()
+// CreateEntry adds an entry to the buffer and checks if it's time to write the buffer.
+func (s *bufferedStore[T]) CreateEntry(ctx context.Context, entry *T) error {
+	// Lock the mutex to ensure only one goroutine can modify the buffer at a time
+	s.mu.Lock()
 
-	// Check for any error in the batch execution
-	if err := br.Close(); err != nil {
-		return fmt.Errorf("batch execution failed: %w", err)
+	// Add the entry to the buffer
+	s.rows = append(s.rows, s.mapper.Row(entry))
+
+	// Hand over a failed background flush
+	if len(s.pending) > 0 {
+		err := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		return err
 	}
 
-	// Clear the batch and reset the timestamp of last execution
-	s.batch = &pgx.Batch{}
-	s.lastExecuted = time.Now()
+	// Check if the buffer size has reached the maximum or if the timeout has elapsed
+	if len(s.rows) < s.maxSize && time.Since(s.lastExecuted) < s.timeout {
+		s.mu.Unlock()
+		return nil
+	}
 
-	return nil
+	// Swap the buffer and write it without holding the mutex
+	rows, number := s.take()
+	s.mu.Unlock()
+
+	return s.write(ctx, rows, number)
 }
 
-func (s *PgBufferedProgramStore) Close() error {
-	// Lock the mutex to ensure only one goroutine can modify the batch at a time
+// flush writes all the buffered rows at once.
+func (s *bufferedStore[T]) flush(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	rows, number := s.take()
+	s.mu.Unlock()
 
-	return s.ExecuteBatch(context.Background())
+	return s.write(ctx, rows, number)
 }
 
-type PgBufferedPopulationStore struct {
-	pool         *pgxpool.Pool
-	batch        *pgx.Batch
-	maxBatchSize int
-	timeout      time.Duration
-	lastExecuted time.Time
-	mu           sync.Mutex // Mutex to protect concurrent access to shared state
+// take swaps the buffer for an empty one, the mutex must be held.
+func (s *bufferedStore[T]) take() ([][]any, int) {
+	rows := s.rows
+	if len(rows) > 0 {
+		s.writes++
+	}
+
+	// Clear the buffer and reset the timestamp of last execution
+	s.rows = make([][]any, 0, s.maxSize)
+	s.lastExecuted = time.Now()
+
+	return rows, s.writes
 }
 
-// NewPgBufferedProgramStore initializes a new PgBufferedEntryStore with a batch size limit and timeout.
-func NewPgBufferedPopulationStore(pool *pgxpool.Pool, maxBatchSize int, timeout time.Duration) EntryStore[PopulationEntry] {
-	return &PgBufferedPopulationStore{
-		pool:         pool,
-		batch:        &pgx.Batch{},
-		maxBatchSize: maxBatchSize,
-		timeout:      timeout,
-		lastExecuted: time.Now(),
+// write sends rows once a slot is free. Rows cancelled while waiting for the slot are buffered
+// again so Close can flush them and ctx's error is returned. Once sent, the rows are not
+// cancelled with ctx, a cancelled import drains the writes in flight instead of losing them,
+// and failed rows are dropped and reported in a BatchError.
+func (s *bufferedStore[T]) write(ctx context.Context, rows [][]any, number int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Wait for a free slot
+	select {
+	case s.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.rows = append(s.rows, rows...)
+		s.mu.Unlock()
+
+		return ctx.Err()
+	}
+
+	// Send the rows to the database, again while it fails with a transient error
+	sendCtx := context.WithoutCancel(ctx)
+	err := s.retry.Do(sendCtx, fmt.Sprintf("%s %d", s.unit, number), func() error {
+		return s.send(sendCtx, rows)
+	})
+	<-s.inFlight
+
+	if err != nil {
+		return &BatchError{Batch: number, Rows: len(rows), Err: err}
 	}
+
+	s.flushed.Add(int64(len(rows)))
+	return nil
 }
 
-// AddEntryToBatch adds an entry to the batch and checks if it's time to execute the batch.
-func (s *PgBufferedPopulationStore) CreateEntry(entry *PopulationEntry) error {
-	// Lock the mutex to ensure only one goroutine can modify the batch at a time
+// Flushed returns the number of entries written to the database.
+func (s *bufferedStore[T]) Flushed() int64 {
+	return s.flushed.Load()
+}
+
+// Close stops the background flusher and flushes the remaining entries, returning the background
+// errors not handed over yet. It does not take a context so it can run after an import is cancelled.
+func (s *bufferedStore[T]) Close() error {
+	s.flusher.Stop()
+
+	err := s.flush(context.Background())
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Add the entry to the batch
-	s.batch.Queue(`
-		INSERT INTO population (year, age, population, district_id)
-		VALUES ($1, $2, $3, $4)
-	`, entry.Year, entry.Age, entry.Population, entry.District)
+	err = errors.Join(append(s.pending, err)...)
+	s.pending = nil
+
+	return err
+}
+
+// PgBufferedStore queues the inserts of its entries and sends them in batches, buffered, sent
+// concurrently and retried by its bufferedStore.
+type PgBufferedStore[T any] struct {
+	*bufferedStore[T]
+}
 
-	// Check if the batch size has reached the maximum or if the timeout has elapsed
-	if s.batch.Len() >= s.maxBatchSize || time.Since(s.lastExecuted) >= s.timeout {
-		if err := s.ExecuteBatch(context.Background()); err != nil {
-			return err
+// NewPgBufferedStore initializes a new PgBufferedStore with a batch size limit, the number of
+// batches sent at once, a retry policy and a timeout. It must be closed to stop its background
+// flusher, and when db is a transaction maxInFlight must be 1 and the policy must not retry.
+func NewPgBufferedStore[T any](db Querier, mapper RowMapper[T], maxBatchSize int, maxInFlight int, retry RetryPolicy, timeout time.Duration) *PgBufferedStore[T] {
+	insert := mapper.Insert()
+	send := func(ctx context.Context, rows [][]any) error {
+		batch := &pgx.Batch{}
+		for _, row := range rows {
+			batch.Queue(insert, row...)
 		}
+
+		return db.SendBatch(ctx, batch).Close()
 	}
 
-	return nil
+	return &PgBufferedStore[T]{newBufferedStore(mapper, "batch", send, maxBatchSize, maxInFlight, retry, timeout)}
 }
 
 // ExecuteBatch sends all the queued inserts in one batch.
-func (s *PgBufferedPopulationStore) ExecuteBatch(ctx context.Context) error {
-	// Send the batch to the database
-	br := s.pool.SendBatch(ctx, s.batch)
-	defer br.Close()
+func (s *PgBufferedStore[T]) ExecuteBatch(ctx context.Context) error {
+	return s.flush(ctx)
+}
 
-	// Check for any error in the batch execution
-	if err := br.Close(); err != nil {
-		return fmt.Errorf("batch execution failed: %w", err)
-	}
+// PgCopyStore buffers the rows of its entries and streams them with the COPY protocol in chunks,
+// buffered, sent concurrently and retried by its bufferedStore like the batches of PgBufferedStore.
+type PgCopyStore[T any] struct {
+	*bufferedStore[T]
+}
 
-	// Clear the batch and reset the timestamp of last execution
-	s.batch = &pgx.Batch{}
-	s.lastExecuted = time.Now()
+// NewPgCopyStore initializes a new PgCopyStore with a chunk size limit, the number of chunks
+// copied at once, a retry policy and a timeout. It must be closed to stop its background
+// flusher, and when db is a transaction maxInFlight must be 1 and the policy must not retry.
+func NewPgCopyStore[T any](db Querier, mapper RowMapper[T], maxChunkSize int, maxInFlight int, retry RetryPolicy, timeout time.Duration) *PgCopyStore[T] {
+	send := func(ctx context.Context, rows [][]any) error {
+		_, err := db.CopyFrom(ctx, pgx.Identifier{mapper.Table}, mapper.Columns, pgx.CopyFromRows(rows))
+		return err
+	}
 
-	return nil
+	return &PgCopyStore[T]{newBufferedStore(mapper, "chunk", send, maxChunkSize, maxInFlight, retry, timeout)}
 }
 
-func (s *PgBufferedPopulationStore) Close() error {
-	// Lock the mutex to ensure only one goroutine can modify the batch at a time
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	return s.ExecuteBatch(context.Background())
+// CopyChunk streams all the buffered rows into the table in one COPY.
+func (s *PgCopyStore[T]) CopyChunk(ctx context.Context) error {
+	return s.flush(ctx)
 }
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// latencyQuerier stands in for the database, every batch or copy takes latency to complete
+// regardless of its size, as a round trip dominated by the network and the commit would.
+// A write cancelled before completing fails with the context's error, and the first failing
+// writes fail with errWrite.
+type latencyQuerier struct {
+	Querier
+	latency time.Duration
+	failing atomic.Int64
+	rows    atomic.Int64
+}
+
+var errWrite = errors.New("write failed")
+
+type latencyResults struct {
+	pgx.BatchResults
+	err error
+}
+
+func (r latencyResults) Close() error {
+	return r.err
+}
+
+// wait waits for the latency to elapse unless ctx is cancelled first.
+func (q *latencyQuerier) wait(ctx context.Context) error {
+	if q.failing.Add(-1) >= 0 {
+		return errWrite
+	}
+
+	select {
+	case <-time.After(q.latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *latencyQuerier) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := q.wait(ctx); err != nil {
+		return latencyResults{err: err}
+	}
+
+	q.rows.Add(int64(b.Len()))
+	return latencyResults{}
+}
+
+func (q *latencyQuerier) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if err := q.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	for rowSrc.Next() {
+		if _, err := rowSrc.Values(); err != nil {
+			return rows, err
+		}
+
+		rows++
+	}
+
+	q.rows.Add(rows)
+	return rows, nil
+}
+
+// storeEntries stores count entries from the given number of workers and closes the store.
+func storeEntries(store EntryStore[ProgramEntry], workers int, count int) error {
+	entries := make(chan ProgramEntry)
+	go func() {
+		defer close(entries)
+
+		date := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < count; i++ {
+			entries <- ProgramEntry{Program: 1, Location: 1 + i%20, Sex: 1 + i%2, EPS: 1 + i%10, Age: i % 100, Birthdate: date, Date: date}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers+1)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for entry := range entries {
+				if err := store.CreateEntry(context.Background(), &entry); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	if closer, ok := store.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			errs <- err
+		}
+	}
+
+	close(errs)
+	return <-errs
+}
+
+func TestStoresFlushEveryEntry(t *testing.T) {
+	const count = 1234
+
+	for _, inFlight := range []int{1, 4} {
+		db := &latencyQuerier{latency: time.Millisecond}
+		buffered := NewPgBufferedStore(db, ProgramRows("entries", 1), 100, inFlight, NewRetryPolicy(0), time.Minute)
+		if err := storeEntries(buffered, 4, count); err != nil {
+			t.Fatalf("buffered, %d in flight: %v", inFlight, err)
+		}
+
+		if db.rows.Load() != count || buffered.Flushed() != count {
+			t.Errorf("buffered, %d in flight: sent %d and flushed %d entries, want %d", inFlight, db.rows.Load(), buffered.Flushed(), count)
+		}
+
+		db = &latencyQuerier{latency: time.Millisecond}
+		copied := NewPgCopyStore(db, ProgramRows("entries", 1), 100, inFlight, NewRetryPolicy(0), time.Minute)
+		if err := storeEntries(copied, 4, count); err != nil {
+			t.Fatalf("copy, %d in flight: %v", inFlight, err)
+		}
+
+		if db.rows.Load() != count || copied.Flushed() != count {
+			t.Errorf("copy, %d in flight: sent %d and flushed %d entries, want %d", inFlight, db.rows.Load(), copied.Flushed(), count)
+		}
+	}
+}
+
+func TestStoresDrainWhenCancelled(t *testing.T) {
+	stores := []struct {
+		name  string
+		store func(db Querier) EntryStore[ProgramEntry]
+	}{
+		{"buffered", func(db Querier) EntryStore[ProgramEntry] {
+			return NewPgBufferedStore(db, ProgramRows("entries", 1), 10, 1, NewRetryPolicy(0), time.Minute)
+		}},
+		{"copy", func(db Querier) EntryStore[ProgramEntry] {
+			return NewPgCopyStore(db, ProgramRows("entries", 1), 10, 1, NewRetryPolicy(0), time.Minute)
+		}},
+	}
+
+	for _, tt := range stores {
+		db := &latencyQuerier{latency: 50 * time.Millisecond}
+		store := tt.store(db)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// the first batch is sent, the second waits for its slot when the import is cancelled
+		errs := make(chan error, 2)
+		for batch := 0; batch < 2; batch++ {
+			for i := 0; i < 9; i++ {
+				if err := store.CreateEntry(ctx, &ProgramEntry{}); err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+			}
+
+			go func() { errs <- store.CreateEntry(ctx, &ProgramEntry{}) }()
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		cancel()
+
+		// the sent batch is written, the waiting one is buffered again
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil && err != context.Canceled {
+				t.Errorf("%s: CreateEntry = %v, want nil or %v", tt.name, err, context.Canceled)
+			}
+		}
+
+		if err := store.(interface{ Close() error }).Close(); err != nil {
+			t.Errorf("%s: Close = %v", tt.name, err)
+		}
+
+		if db.rows.Load() != 20 {
+			t.Errorf("%s: %d rows written, want 20", tt.name, db.rows.Load())
+		}
+	}
+}
+
+// The benchmarks store 8000 entries from 8 workers in batches of 500 against a database taking
+// 20ms per round trip, so they measure how well sending batches concurrently hides the latency.
+const (
+	benchEntries   = 8000
+	benchWorkers   = 8
+	benchBatchSize = 500
+	benchLatency   = 20 * time.Millisecond
+)
+
+func benchmarkStore(b *testing.B, newStore func(db Querier, inFlight int) EntryStore[ProgramEntry]) {
+	for _, inFlight := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("inflight-%d", inFlight), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				db := &latencyQuerier{latency: benchLatency}
+				if err := storeEntries(newStore(db, inFlight), benchWorkers, benchEntries); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportMetric(float64(b.N*benchEntries)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+func BenchmarkPgBufferedStore(b *testing.B) {
+	benchmarkStore(b, func(db Querier, inFlight int) EntryStore[ProgramEntry] {
+		return NewPgBufferedStore(db, ProgramRows("entries", 1), benchBatchSize, inFlight, NewRetryPolicy(0), time.Minute)
+	})
+}
+
+func BenchmarkPgCopyStore(b *testing.B) {
+	benchmarkStore(b, func(db Querier, inFlight int) EntryStore[ProgramEntry] {
+		return NewPgCopyStore(db, ProgramRows("entries", 1), benchBatchSize, inFlight, NewRetryPolicy(0), time.Minute)
+	})
+}
@@ -30,6 +30,7 @@ const (
 )
 
 const (
+	LocationCity          = 0
 	LocationUsaquen       = 1
 	LocationChapinero     = 2
 	LocationSantaFe       = 3
@@ -54,18 +55,21 @@ const (
 )
 
 type ProgramEntry struct {
-	Program  int
-	Location int
-	Sex      int
-	EPS      int
-	Age      int
-	Date     time.Time
+	Program   int
+	Location  int
+	Sex       int
+	EPS       int
+	Age       int
+	Birthdate time.Time
+	Date      time.Time
 }
 
-
 type PopulationEntry struct {
 	Year       int
 	Age        int
+	Sex        int
+	LifeCourse *int
+	AgeGroup   *int
 	Population int
 	District   int
 }